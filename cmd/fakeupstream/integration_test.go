@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/controllers"
+	"beego-api-service/internal/fakeupstream"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixtureID = "HA-3213808988"
+
+// startService points the service at a fake upstream serving the fixtures.
+func startService(t *testing.T, scenario fakeupstream.Scenario) {
+	server := httptest.NewServer(fakeupstream.New("fixtures", scenario, 1))
+	web.AppConfig.Set("externalAPIBaseURL", server.URL+"/dynamodb-s3-os")
+	web.AppConfig.Set("upstreamRetryMaxAttempts", "1")
+	t.Cleanup(func() {
+		server.Close()
+		web.AppConfig.Set("externalAPIBaseURL", "")
+		web.AppConfig.Set("upstreamRetryMaxAttempts", "")
+	})
+}
+
+func newContext(target string) (*context.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("GET", target, nil))
+	return ctx, w
+}
+
+func TestServiceAgainstFakeUpstream(t *testing.T) {
+	startService(t, fakeupstream.Scenario{NotFoundIDs: []string{"HA-404"}})
+
+	t.Run("Details", func(t *testing.T) {
+		ctx, w := newContext("/v1/api/property/details/" + fixtureID)
+		ctx.Input.SetParam(":propertyId", fixtureID)
+		controller := &controllers.PropertyDetailsController{}
+		controller.Init(ctx, "", "", nil)
+
+		controller.GetPropertyDetails()
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var details structs.PropertyDetailsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+		assert.Equal(t, fixtureID, details.ID)
+		assert.Equal(t, "Brand New Luxury Penthouse w/Jacuzzi", details.Property.PropertyName)
+	})
+
+	t.Run("Bulk", func(t *testing.T) {
+		ctx, w := newContext("/v1/api/propertyList?propertyIds=" + fixtureID + ",HA-404")
+		controller := &controllers.BulkPropertyFetchController{}
+		controller.Init(ctx, "", "", nil)
+
+		controller.BulkPropertyFetch()
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response structs.BulkPropertyResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, structs.BulkSummary{Total: 2, OK: 1, NotFound: 1}, response.Summary)
+		if assert.Len(t, response.Items, 2) {
+			assert.Equal(t, structs.BulkItemOK, response.Items[0].Status)
+			assert.Equal(t, structs.BulkItemNotFound, response.Items[1].Status)
+		}
+	})
+}
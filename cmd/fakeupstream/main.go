@@ -0,0 +1,59 @@
+// Command fakeupstream serves property documents from a fixtures directory
+// the way the dynamodb-s3-os API does, optionally injecting latency, errors,
+// truncated bodies and missing properties.
+//
+// Point the service at it with
+//
+//	externalAPIBaseURL = "http://localhost:8085/dynamodb-s3-os"
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+)
+
+func main() {
+	addr := flag.String("addr", ":8085", "address to listen on")
+	fixturesDir := flag.String("fixtures", "cmd/fakeupstream/fixtures", "directory of <propertyId>/<languageCode>.json documents")
+	scenarioFile := flag.String("scenario", "", "JSON file with the scenario to start with")
+	latencyMs := flag.Int("latency-ms", 0, "delay added to every response")
+	jitterMs := flag.Int("jitter-ms", 0, "random extra delay, up to this many milliseconds")
+	errorRate := flag.Float64("error-rate", 0, "fraction of requests answered with -error-status")
+	errorStatus := flag.Int("error-status", http.StatusServiceUnavailable, "status of injected errors")
+	failFirst := flag.Int("fail-first", 0, "requests per property answered with -error-status before any succeeds")
+	truncateRate := flag.Float64("truncate-rate", 0, "fraction of documents cut off half way")
+	notFound := flag.String("not-found", "", "comma-separated property IDs answered with 404")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for injected randomness")
+	flag.Parse()
+
+	scenario := fakeupstream.Scenario{
+		LatencyMs:    *latencyMs,
+		JitterMs:     *jitterMs,
+		ErrorRate:    *errorRate,
+		ErrorStatus:  *errorStatus,
+		FailFirst:    *failFirst,
+		TruncateRate: *truncateRate,
+	}
+	if *scenarioFile != "" {
+		loaded, err := fakeupstream.LoadScenario(*scenarioFile)
+		if err != nil {
+			log.Fatalf("failed to load scenario %s: %v", *scenarioFile, err)
+		}
+		scenario = loaded
+	}
+	for _, id := range strings.Split(*notFound, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			scenario.NotFoundIDs = append(scenario.NotFoundIDs, id)
+		}
+	}
+
+	log.Printf("serving fixtures from %s on %s", *fixturesDir, *addr)
+	if err := http.ListenAndServe(*addr, fakeupstream.New(*fixturesDir, scenario, *seed)); err != nil {
+		log.Fatalf("fake upstream stopped: %v", err)
+	}
+}
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"beego-api-service/requests"
 	"beego-api-service/responses"
@@ -19,31 +23,262 @@ type BulkPropertyFetchController struct {
 
 func (c *BulkPropertyFetchController) BulkPropertyFetch() {
 	ids, err := requests.GetPropertyIDs(&c.Controller)
+	if err != nil {
+		sendPropertyIDsError(&c.Controller, err)
+		return
+	}
+
+	lang, err := requests.GetLanguage(&c.Controller)
 	if err != nil {
 		log.Println(err)
-		responses.SendErrorResponse(&c.Controller, "No property IDs provided", http.StatusBadRequest)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	source, err := requests.GetSource(&c.Controller, services.SourceOS)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedSource, "Unsupported source", http.StatusBadRequest)
+		return
+	}
+
+	withProvenance, err := requests.GetWithProvenance(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorInvalidParameter, "Invalid withProvenance value", http.StatusBadRequest)
+		return
+	}
+
+	fields, fieldPaths, err := requests.GetFields(&c.Controller)
+	if err != nil {
+		sendUnknownFieldsError(&c.Controller, "fields", err)
+		return
+	}
+
+	c.fetchProperties(requests.BulkPropertyFetch{
+		IDs:            ids,
+		Options:        services.FetchOptions{Language: lang, Source: source},
+		Fields:         fields,
+		FieldPaths:     fieldPaths,
+		WithProvenance: withProvenance,
+	})
+}
+
+// PostBulkPropertyFetch is BulkPropertyFetch for a JSON body, for lists of
+// IDs too long for a query string.
+func (c *BulkPropertyFetchController) PostBulkPropertyFetch() {
+	request, err := requests.GetBulkPropertyFetch(&c.Controller)
+	if err != nil {
+		sendRequestError(&c.Controller, err)
+		return
+	}
+
+	if len(request.FieldPaths) == 0 {
+		if request.Fields, request.FieldPaths, err = requests.GetFields(&c.Controller); err != nil {
+			sendUnknownFieldsError(&c.Controller, "fields", err)
+			return
+		}
+	}
+
+	c.fetchProperties(request)
+}
+
+// fetchProperties fetches every requested property on the bulk worker pool
+// and answers with one item per ID. When the client accepts NDJSON or
+// server-sent events, each item is streamed as soon as it is fetched,
+// followed by a summary record. CSV and XLSX exports get one row per ID,
+// streamed in request order.
+func (c *BulkPropertyFetchController) fetchProperties(request requests.BulkPropertyFetch) {
+	ids, opts, withProvenance := request.IDs, request.Options, request.WithProvenance
+
+	mediaType, err := requests.GetBulkMediaType(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedFormat, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+	if mediaType == "" && notAcceptable(&c.Controller) {
 		return
 	}
 
-	var wg sync.WaitGroup
+	var columns []services.ExportColumn
+	exporting := mediaType == responses.MediaTypeCSV || mediaType == responses.MediaTypeXLSX
+	if exporting {
+		if columns, err = requests.GetExportColumns(&c.Controller, request.FieldPaths); err != nil {
+			sendUnknownFieldsError(&c.Controller, "columns", err)
+			return
+		}
+		// The columns select what is exported
+		request.Fields = nil
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "bulkDeadlineMs", 30000)
+	defer cancel()
+
+	var stream *responses.BulkStream
+	var table responses.TableWriter
+	if mediaType != "" {
+		c.Ctx.Output.Header("Vary", "Accept, Accept-Language")
+	}
+	if exporting {
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.Header
+		}
+		if table, err = responses.StartTableExport(&c.Controller, mediaType, "properties", header); err != nil {
+			log.Printf("Failed to start export: %v", err)
+			return
+		}
+	} else if mediaType != "" {
+		stream = responses.StartBulkStream(&c.Controller, mediaType)
+	}
+
 	var mu sync.Mutex
-	results := make([]structs.PropertyDetailsResponse, len(ids))
-
-	for i, id := range ids {
-		wg.Add(1)
-		go func(i int, id string) {
-			defer wg.Done()
-			data, err := services.FetchOSPropertyDetails(id)
-			if err != nil {
-				log.Printf("Error fetching details for property ID %s: %v", id, err)
-				return
+	var meta services.FetchMeta
+	var circuitErr error
+	// unanswered counts the properties refused by the open circuit or cut off
+	// by the deadline.
+	unanswered := 0
+	items := make([]structs.BulkItem, len(ids))
+	fetched := make([]structs.BulkItem, 0, len(ids))
+	// Export rows are written in request order; exported counts the rows
+	// written so far.
+	ready := make([]bool, len(ids))
+	exported := 0
+
+	services.ForEachProperty(ctx, ids, func(i int, id string) {
+		start := time.Now()
+		var data structs.PropertyDetailsWithProvenance
+		var fetchMeta services.FetchMeta
+		var err error
+		if withProvenance {
+			data, fetchMeta, err = services.FetchPropertyDetailsWithProvenance(ctx, id, opts)
+		} else {
+			data.Details, fetchMeta, err = services.FetchOSPropertyDetails(ctx, id, opts)
+		}
+		item := bulkItem(id, data, request.Fields, err, time.Since(start))
+		mu.Lock()
+		defer mu.Unlock()
+		meta = meta.Merge(fetchMeta)
+		items[i] = item
+		ready[i] = true
+		fetched = append(fetched, item)
+		if circuitOpen(err) {
+			circuitErr = err
+		}
+		if circuitOpen(err) || (err != nil && ctx.Err() != nil) {
+			unanswered++
+		}
+		if stream != nil {
+			if err := stream.WriteItem(item); err != nil {
+				log.Printf("Failed to stream property ID %s: %v", id, err)
+				cancel()
+			}
+		}
+		for ; table != nil && exported < len(ids) && ready[exported]; exported++ {
+			if err := table.WriteRow(services.ExportRow(items[exported], columns)); err != nil {
+				log.Printf("Failed to export property ID %s: %v", ids[exported], err)
+				cancel()
+				table = nil
 			}
-			mu.Lock()
-			results[i] = data
-			mu.Unlock()
-		}(i, id)
+		}
+	})
+
+	if exporting {
+		c.finishExport(ctx, table, ids, items, ready, exported, columns)
+		return
+	}
+
+	if stream != nil {
+		summary := structs.BulkStreamSummary{Summary: services.SummarizeBulkItems(fetched).Summary}
+		summary.Summary.Total = len(ids)
+		if err := ctx.Err(); err != nil {
+			body, _ := fetchErrorResponse(err)
+			summary.Error = &body
+		}
+		if err := stream.WriteSummary(summary); err != nil {
+			log.Printf("Failed to stream bulk summary: %v", err)
+		}
+		return
+	}
+
+	setFetchHeaders(&c.Controller, meta)
+	// The whole request fails only when no property got past the open circuit
+	// or the deadline; otherwise those properties are reported as items.
+	if len(fetched) == unanswered {
+		if circuitErr != nil {
+			sendFetchError(&c.Controller, circuitErr)
+			return
+		}
+		if err := ctx.Err(); err != nil {
+			sendFetchError(&c.Controller, err)
+			return
+		}
+	}
+	for i := range items {
+		if !ready[i] {
+			items[i] = bulkItem(ids[i], structs.PropertyDetailsWithProvenance{}, nil, ctx.Err(), 0)
+		}
 	}
-	wg.Wait()
+	responses.SendBulkPropertyResponse(&c.Controller, services.SummarizeBulkItems(items))
+}
+
+// finishExport writes the rows not exported yet, reporting properties that
+// were skipped when the deadline passed, and closes the file. table is nil
+// when writing has already failed.
+func (c *BulkPropertyFetchController) finishExport(ctx context.Context, table responses.TableWriter, ids []string, items []structs.BulkItem, ready []bool, exported int, columns []services.ExportColumn) {
+	if table == nil {
+		return
+	}
+	for ; exported < len(ids); exported++ {
+		item := items[exported]
+		if !ready[exported] {
+			item = bulkItem(ids[exported], structs.PropertyDetailsWithProvenance{}, nil, ctx.Err(), 0)
+		}
+		if err := table.WriteRow(services.ExportRow(item, columns)); err != nil {
+			log.Printf("Failed to export property ID %s: %v", ids[exported], err)
+			return
+		}
+	}
+	if err := table.Close(); err != nil {
+		log.Printf("Failed to finish export: %v", err)
+	}
+}
+
+// bulkItem reports the outcome of fetching one property of a bulk request,
+// keeping only the selected fields of its details.
+func bulkItem(id string, data structs.PropertyDetailsWithProvenance, fields services.FieldSet, err error, latency time.Duration) structs.BulkItem {
+	item := structs.BulkItem{ID: id, LatencyMs: latency.Milliseconds()}
+	var projected interface{}
+	if err == nil {
+		projected, err = fields.Project(data.Details)
+	}
+	if err != nil {
+		log.Printf("Error fetching details for property ID %s: %v", id, err)
+		body, _ := fetchErrorResponse(err)
+		item.Status = structs.BulkItemError
+		if services.ErrorCodeOf(err) == services.ErrorNotFound {
+			item.Status = structs.BulkItemNotFound
+		}
+		item.Error = &body
+		return item
+	}
+	item.Status = structs.BulkItemOK
+	item.Data = projected
+	item.Provenance = fields.SelectProvenance(data.Provenance)
+	return item
+}
 
-	responses.SendPropertyDetailsResponses(&c.Controller, results)
+// sendPropertyIDsError answers a request whose propertyIds could not be
+// read, telling the client when it asked for too many or which entries are
+// blank.
+func sendPropertyIDsError(c *web.Controller, err error) {
+	log.Println(err)
+	var invalidErr *requests.InvalidRequestError
+	if errors.As(err, &invalidErr) {
+		sendRequestError(c, err)
+		return
+	}
+	if errors.Is(err, requests.ErrTooManyPropertyIDs) {
+		responses.SendErrorResponse(c, errorTooManyPropertyIDs, fmt.Sprintf("Too many property IDs, at most %d are allowed", requests.MaxPropertyIDs()), http.StatusBadRequest)
+		return
+	}
+	responses.SendErrorResponse(c, errorMissingParameter, "No property IDs provided", http.StatusBadRequest)
 }
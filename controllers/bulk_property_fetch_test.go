@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUpstream serves OS documents for any property ID, except that IDs
+// starting with "missing" are not found, "fail" answer 500, "late" answer
+// after a short delay and "slow" never answer before the request ends.
+type fakeUpstream struct {
+	*httptest.Server
+	requests int32
+}
+
+func newFakeUpstream(t *testing.T, config map[string]string) *fakeUpstream {
+	upstream := &fakeUpstream{}
+	upstream.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstream.requests, 1)
+		id := r.URL.Query().Get("propertyId")
+		switch {
+		case strings.HasPrefix(id, "missing"):
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case strings.HasPrefix(id, "fail"):
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case strings.HasPrefix(id, "late"):
+			time.Sleep(50 * time.Millisecond)
+		case strings.HasPrefix(id, "slow"):
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"OS": map[string]interface{}{"id": id, "property_name": "Property " + id, "published": true},
+		})
+	}))
+
+	config["externalAPIBaseURL"] = upstream.URL
+	config["upstreamRetryMaxAttempts"] = "1"
+	for key, value := range config {
+		web.AppConfig.Set(key, value)
+	}
+	t.Cleanup(func() {
+		upstream.Close()
+		for key := range config {
+			web.AppConfig.Set(key, "")
+		}
+	})
+	return upstream
+}
+
+// newBulkController returns a controller serving a GET of target.
+func newBulkController(target, accept string) (*BulkPropertyFetchController, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", accept)
+	ctx := context.NewContext()
+	ctx.Reset(w, req)
+
+	controller := &BulkPropertyFetchController{}
+	controller.Init(ctx, "", "", nil)
+	return controller, w
+}
+
+func TestBulkPropertyFetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        string
+		accept     string
+		config     map[string]string
+		status     int
+		summary    structs.BulkSummary
+		statuses   []string
+		errorCode  string
+		noUpstream bool
+	}{
+		{
+			name:     "Items in request order",
+			ids:      "late-1,missing-2,HA-3",
+			config:   map[string]string{"bulkConcurrency": "3"},
+			status:   http.StatusOK,
+			summary:  structs.BulkSummary{Total: 3, OK: 2, NotFound: 1},
+			statuses: []string{structs.BulkItemOK, structs.BulkItemNotFound, structs.BulkItemOK},
+		},
+		{
+			name:     "Properties past the deadline are failed items",
+			ids:      "HA-1,slow-2,HA-3",
+			config:   map[string]string{"bulkConcurrency": "1", "bulkDeadlineMs": "200"},
+			status:   http.StatusOK,
+			summary:  structs.BulkSummary{Total: 3, OK: 1, Error: 2},
+			statuses: []string{structs.BulkItemOK, structs.BulkItemError, structs.BulkItemError},
+		},
+		{
+			name:      "Deadline before any property is fetched",
+			ids:       "slow-1,HA-2",
+			config:    map[string]string{"bulkConcurrency": "1", "bulkDeadlineMs": "100"},
+			status:    http.StatusGatewayTimeout,
+			errorCode: "upstream_timeout",
+		},
+		{
+			name:       "Blank IDs are rejected before fetching",
+			ids:        "HA-1,,HA-2",
+			config:     map[string]string{},
+			status:     http.StatusBadRequest,
+			errorCode:  "invalid_request",
+			noUpstream: true,
+		},
+		{
+			name:       "Not acceptable before fetching",
+			ids:        "HA-1",
+			accept:     "text/html",
+			config:     map[string]string{},
+			status:     http.StatusNotAcceptable,
+			errorCode:  "not_acceptable",
+			noUpstream: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newFakeUpstream(t, tt.config)
+			controller, w := newBulkController("/v1/api/propertyList?propertyIds="+tt.ids, tt.accept)
+
+			controller.BulkPropertyFetch()
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.noUpstream {
+				assert.Equal(t, int32(0), atomic.LoadInt32(&upstream.requests))
+			}
+			if tt.errorCode != "" {
+				var problem structs.Problem
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				assert.Equal(t, tt.errorCode, problem.Code)
+				return
+			}
+
+			var response structs.BulkPropertyResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.summary, response.Summary)
+			var ids, statuses []string
+			for _, item := range response.Items {
+				ids = append(ids, item.ID)
+				statuses = append(statuses, item.Status)
+			}
+			assert.Equal(t, strings.Split(tt.ids, ","), ids)
+			assert.Equal(t, tt.statuses, statuses)
+		})
+	}
+}
+
+func TestBulkPropertyFetchCircuitOpen(t *testing.T) {
+	newFakeUpstream(t, map[string]string{
+		"bulkConcurrency":           "1",
+		"circuitBreakerMinRequests": "1",
+	})
+
+	// The failure opens the circuit, which then refuses HA-2.
+	controller, w := newBulkController("/v1/api/propertyList?propertyIds=fail-1,HA-2", "")
+	controller.BulkPropertyFetch()
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response structs.BulkPropertyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, structs.BulkSummary{Total: 2, Error: 2}, response.Summary)
+	assert.Equal(t, "upstream_unavailable", response.Items[1].Error.Code)
+
+	// With every property refused, the whole request is unavailable.
+	controller, w = newBulkController("/v1/api/propertyList?propertyIds=HA-3", "")
+	controller.BulkPropertyFetch()
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestBulkPropertyFetchStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     string
+		config  map[string]string
+		items   []string
+		summary string
+	}{
+		{
+			name:    "Summary after the items",
+			ids:     "HA-1,missing-2",
+			config:  map[string]string{"bulkConcurrency": "1"},
+			items:   []string{"HA-1", "missing-2"},
+			summary: `{"Summary":{"Total":2,"OK":1,"NotFound":1,"Error":0}}`,
+		},
+		{
+			name:   "Summary reports the deadline",
+			ids:    "HA-1,slow-2,HA-3",
+			config: map[string]string{"bulkConcurrency": "1", "bulkDeadlineMs": "200"},
+			items:  []string{"HA-1", "slow-2"},
+			summary: `{"Summary":{"Total":3,"OK":1,"NotFound":0,"Error":1},` +
+				`"Error":{"Code":"upstream_timeout","Message":"Request timed out"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeUpstream(t, tt.config)
+			controller, w := newBulkController("/v1/api/propertyList?propertyIds="+tt.ids, "application/x-ndjson")
+
+			controller.BulkPropertyFetch()
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/x-ndjson; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept, Accept-Language", w.Header().Get("Vary"))
+
+			var lines []string
+			scanner := bufio.NewScanner(w.Body)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if !assert.Len(t, lines, len(tt.items)+1) {
+				return
+			}
+			for i, id := range tt.items {
+				var item structs.BulkItem
+				assert.NoError(t, json.Unmarshal([]byte(lines[i]), &item))
+				assert.Equal(t, id, item.ID)
+			}
+			assert.JSONEq(t, tt.summary, lines[len(lines)-1])
+		})
+	}
+}
+
+func TestBulkPropertyFetchExport(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    string
+		config map[string]string
+		rows   [][]string
+	}{
+		{
+			name:   "Rows in request order",
+			ids:    "late-1,missing-2,HA-3",
+			config: map[string]string{"bulkConcurrency": "3"},
+			rows: [][]string{
+				{"ID", "Name", "Status"},
+				{"late-1", "Property late-1", "ok"},
+				{"missing-2", "", "not_found"},
+				{"HA-3", "Property HA-3", "ok"},
+			},
+		},
+		{
+			name:   "Properties past the deadline are exported as failed",
+			ids:    "HA-1,slow-2,HA-3",
+			config: map[string]string{"bulkConcurrency": "1", "bulkDeadlineMs": "200"},
+			rows: [][]string{
+				{"ID", "Name", "Status"},
+				{"HA-1", "Property HA-1", "ok"},
+				{"slow-2", "", "error"},
+				{"HA-3", "", "error"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeUpstream(t, tt.config)
+			controller, w := newBulkController("/v1/api/propertyList?format=csv&columns=ID,Property.PropertyName:Name,Status&propertyIds="+tt.ids, "")
+
+			controller.BulkPropertyFetch()
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `attachment; filename="properties.csv"`, w.Header().Get("Content-Disposition"))
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rows, rows)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"beego-api-service/requests"
+	"beego-api-service/responses"
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type PropertyDiscrepanciesController struct {
+	web.Controller
+}
+
+func (c *PropertyDiscrepanciesController) GetPropertyDiscrepancies() {
+	propertyId, err := requests.GetPropertyID(&c.Controller)
+	if err != nil {
+		log.Println(err)
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Property ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	lang, err := requests.GetLanguage(&c.Controller)
+	if err != nil {
+		log.Println(err)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "discrepanciesDeadlineMs", 10000)
+	defer cancel()
+
+	report, meta, err := services.FetchPropertyDiscrepancies(ctx, propertyId, services.FetchOptions{Language: lang})
+	setFetchHeaders(&c.Controller, meta)
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	responses.SendDiscrepancyReportResponse(&c.Controller, report)
+}
+
+// ReconcileProperties compares the S3 and OS views of every requested
+// property. Properties that cannot be compared are reported with their error
+// instead of failing the whole request.
+func (c *PropertyDiscrepanciesController) ReconcileProperties() {
+	ids, err := requests.GetPropertyIDs(&c.Controller)
+	if err != nil {
+		sendPropertyIDsError(&c.Controller, err)
+		return
+	}
+
+	lang, err := requests.GetLanguage(&c.Controller)
+	if err != nil {
+		log.Println(err)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "bulkDeadlineMs", 30000)
+	defer cancel()
+
+	var mu sync.Mutex
+	var meta services.FetchMeta
+	reports := make([]structs.DiscrepancyReport, len(ids))
+	ready := make([]bool, len(ids))
+	// compared counts the properties that got an answer before the deadline.
+	compared := 0
+
+	services.ForEachProperty(ctx, ids, func(i int, id string) {
+		report, fetchMeta, err := services.FetchPropertyDiscrepancies(ctx, id, services.FetchOptions{Language: lang})
+		if err != nil {
+			log.Printf("Error comparing property ID %s: %v", id, err)
+			body, _ := fetchErrorResponse(err)
+			report.Error = &body
+		}
+		mu.Lock()
+		meta = meta.Merge(fetchMeta)
+		reports[i] = report
+		ready[i] = true
+		if err == nil || ctx.Err() == nil {
+			compared++
+		}
+		mu.Unlock()
+	})
+
+	setFetchHeaders(&c.Controller, meta)
+	// Like the bulk endpoints, the whole request fails only when the deadline
+	// cut off every property; otherwise they are reported one by one.
+	if err := ctx.Err(); err != nil {
+		if compared == 0 {
+			sendFetchError(&c.Controller, err)
+			return
+		}
+		body, _ := fetchErrorResponse(err)
+		for i := range reports {
+			if !ready[i] {
+				reports[i] = structs.DiscrepancyReport{ID: ids[i], Discrepancies: []structs.FieldDiscrepancy{}, Error: &body}
+			}
+		}
+	}
+	responses.SendReconciliationReportResponse(&c.Controller, services.ReconcileReports(reports))
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileProperties(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    string
+		status int
+		codes  []string
+	}{
+		{
+			name:   "Properties past the deadline are reported as failed",
+			ids:    "HA-1,slow-2,HA-3",
+			status: http.StatusOK,
+			// The fake upstream has no S3 block, so HA-1 cannot be compared either
+			codes: []string{"bad_upstream_payload", "", "upstream_timeout"},
+		},
+		{
+			name:   "Deadline before any property is compared",
+			ids:    "slow-1,HA-2",
+			status: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeUpstream(t, map[string]string{"bulkConcurrency": "1", "bulkDeadlineMs": "200"})
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/v1/api/propertyList/discrepancies?propertyIds="+tt.ids, nil))
+			controller := &PropertyDiscrepanciesController{}
+			controller.Init(ctx, "", "", nil)
+
+			controller.ReconcileProperties()
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.codes == nil {
+				return
+			}
+			var report structs.ReconciliationReport
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, len(tt.codes), report.Summary.Total)
+			assert.Equal(t, len(tt.codes), report.Summary.Failed)
+			for i, code := range tt.codes {
+				if assert.NotNil(t, report.Properties[i].Error) && code != "" {
+					assert.Equal(t, code, report.Properties[i].Error.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"beego-api-service/requests"
+	"beego-api-service/responses"
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// statusClientClosedRequest is the non-standard status logged when the client
+// went away before the response was ready.
+const statusClientClosedRequest = 499
+
+// Error codes of requests rejected before any property is fetched.
+const (
+	// errorInvalidRequest is a request body that failed validation.
+	errorInvalidRequest      = "invalid_request"
+	errorMissingParameter    = "missing_parameter"
+	errorInvalidParameter    = "invalid_parameter"
+	errorUnsupportedLanguage = "unsupported_language"
+	errorUnsupportedSource   = "unsupported_source"
+	errorUnsupportedFormat   = "unsupported_format"
+	errorTooManyPropertyIDs  = "too_many_property_ids"
+	errorRequestTooLarge     = "request_too_large"
+)
+
+// fetchErrors maps service error codes to the HTTP status and message sent
+// to clients.
+var fetchErrors = map[services.ErrorCode]struct {
+	status  int
+	message string
+}{
+	services.ErrorNotFound:            {http.StatusNotFound, "Property not found"},
+	services.ErrorUpstreamUnavailable: {http.StatusServiceUnavailable, "Upstream service unavailable"},
+	services.ErrorBadUpstreamPayload:  {http.StatusBadGateway, "Upstream returned an invalid property document"},
+	services.ErrorTimeout:             {http.StatusGatewayTimeout, "Request timed out"},
+	services.ErrorCanceled:            {statusClientClosedRequest, "Request canceled"},
+	services.ErrorInternal:            {http.StatusInternalServerError, "Internal server error"},
+}
+
+// sendFetchError answers a failed service call with the status and error
+// code matching the service error. A Retry-After header is added when the
+// upstream or the open circuit breaker told us how long to wait.
+func sendFetchError(c *web.Controller, err error) {
+	log.Println(err)
+
+	if retryAfter := services.RetryAfterOf(err); retryAfter > 0 {
+		c.Ctx.Output.Header("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+	}
+
+	body, status := fetchErrorResponse(err)
+	responses.SendErrorCodeResponse(c, body, status)
+}
+
+// notAcceptable answers 406 Not Acceptable when the client accepts none of
+// the response media types, so that nothing is fetched for it.
+func notAcceptable(c *web.Controller) bool {
+	if _, ok := responses.NegotiateMediaType(c.Ctx.Input.Header("Accept")); ok {
+		return false
+	}
+	responses.SendNotAcceptableResponse(c)
+	return true
+}
+
+// sendRequestError answers a request body that failed validation, listing
+// every offending entry.
+func sendRequestError(c *web.Controller, err error) {
+	if errors.Is(err, requests.ErrRequestBodyTooLarge) {
+		responses.SendErrorResponse(c, errorRequestTooLarge, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var invalidErr *requests.InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		sendFetchError(c, err)
+		return
+	}
+	responses.SendErrorCodeResponse(c, structs.ErrorResponse{
+		Code:    errorInvalidRequest,
+		Message: invalidErr.Message,
+		Fields:  invalidErr.Fields,
+	}, http.StatusBadRequest)
+}
+
+// sendUnknownFieldsError answers a request whose param names fields that do
+// not exist, listing each of them.
+func sendUnknownFieldsError(c *web.Controller, param string, err error) {
+	var unknownErr *services.UnknownFieldsError
+	if !errors.As(err, &unknownErr) {
+		sendFetchError(c, err)
+		return
+	}
+	body := structs.ErrorResponse{Code: errorInvalidRequest, Message: "Unknown fields in " + param}
+	for _, field := range unknownErr.Fields {
+		body.Fields = append(body.Fields, structs.FieldError{Path: param, Reason: "unknown field: " + field})
+	}
+	responses.SendErrorCodeResponse(c, body, http.StatusBadRequest)
+}
+
+// fetchErrorResponse builds the error body and HTTP status for a failed
+// service call. Invalid upstream documents list the offending fields.
+func fetchErrorResponse(err error) (structs.ErrorResponse, int) {
+	code := services.ErrorCodeOf(err)
+	mapped, ok := fetchErrors[code]
+	if !ok {
+		mapped = fetchErrors[services.ErrorInternal]
+	}
+
+	body := structs.ErrorResponse{Code: string(code), Message: mapped.message}
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		body.Fields = validationErr.Fields
+	}
+	return body, mapped.status
+}
+
+// circuitOpen reports whether err came from the open circuit breaker.
+func circuitOpen(err error) bool {
+	var upstreamErr *services.UpstreamError
+	return errors.As(err, &upstreamErr) && upstreamErr.Kind == services.UpstreamErrorCircuitOpen
+}
+
+// retryAfterSeconds rounds d up to whole seconds, never below one.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   structs.ErrorResponse
+		status int
+	}{
+		{
+			name:   "Not found",
+			err:    &services.ServiceError{Code: services.ErrorNotFound, Err: errors.New("404")},
+			want:   structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+			status: http.StatusNotFound,
+		},
+		{
+			name: "Open circuit",
+			err: &services.ServiceError{
+				Code: services.ErrorUpstreamUnavailable,
+				Err:  &services.UpstreamError{Kind: services.UpstreamErrorCircuitOpen, RetryAfter: time.Second},
+			},
+			want:   structs.ErrorResponse{Code: "upstream_unavailable", Message: "Upstream service unavailable"},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "Invalid payload lists the fields",
+			err: &services.ServiceError{
+				Code: services.ErrorBadUpstreamPayload,
+				Err:  &services.ValidationError{Fields: []structs.FieldError{{Path: "S3.ID", Reason: "is required"}}},
+			},
+			want: structs.ErrorResponse{
+				Code:    "bad_upstream_payload",
+				Message: "Upstream returned an invalid property document",
+				Fields:  []structs.FieldError{{Path: "S3.ID", Reason: "is required"}},
+			},
+			status: http.StatusBadGateway,
+		},
+		{
+			name:   "Deadline",
+			err:    context.DeadlineExceeded,
+			want:   structs.ErrorResponse{Code: "upstream_timeout", Message: "Request timed out"},
+			status: http.StatusGatewayTimeout,
+		},
+		{
+			name:   "Client gone",
+			err:    context.Canceled,
+			want:   structs.ErrorResponse{Code: "request_canceled", Message: "Request canceled"},
+			status: statusClientClosedRequest,
+		},
+		{
+			name:   "Unknown error",
+			err:    errors.New("boom"),
+			want:   structs.ErrorResponse{Code: "internal_error", Message: "Internal server error"},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, status := fetchErrorResponse(tt.err)
+			assert.Equal(t, tt.want, body)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(200*time.Millisecond))
+	assert.Equal(t, 3, retryAfterSeconds(2500*time.Millisecond))
+}
+
+func TestNotAcceptableBeforeFetching(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		serve  func(ctx *beecontext.Context)
+	}{
+		{
+			name:   "Details",
+			accept: "text/html",
+			serve: func(ctx *beecontext.Context) {
+				c := &PropertyDetailsController{}
+				c.Init(ctx, "", "", nil)
+				c.GetPropertyDetails()
+			},
+		},
+		{
+			name:   "Gallery",
+			accept: "image/png",
+			serve: func(ctx *beecontext.Context) {
+				c := &PropertyImagesController{}
+				c.Init(ctx, "", "", nil)
+				c.GetPropertyImages()
+			},
+		},
+		{
+			name:   "Full",
+			accept: "text/html",
+			serve: func(ctx *beecontext.Context) {
+				c := &PropertyFullController{}
+				c.Init(ctx, "", "", nil)
+				c.GetPropertyFull()
+			},
+		},
+		{
+			name:   "Discrepancies",
+			accept: "text/plain",
+			serve: func(ctx *beecontext.Context) {
+				c := &PropertyDiscrepanciesController{}
+				c.Init(ctx, "", "", nil)
+				c.GetPropertyDiscrepancies()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newFakeUpstream(t, map[string]string{})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/v1/api/property/HA-1", nil)
+			req.Header.Set("Accept", tt.accept)
+			ctx := beecontext.NewContext()
+			ctx.Reset(w, req)
+			ctx.Input.SetParam(":propertyId", "HA-1")
+
+			tt.serve(ctx)
+
+			assert.Equal(t, http.StatusNotAcceptable, w.Code)
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.Equal(t, int32(0), atomic.LoadInt32(&upstream.requests))
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"strconv"
+
+	"beego-api-service/services"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// setFetchHeaders reports how the upstream data behind the response was obtained.
+func setFetchHeaders(c *web.Controller, meta services.FetchMeta) {
+	if meta.CacheStatus != "" {
+		c.Ctx.Output.Header("X-Cache", string(meta.CacheStatus))
+	}
+	if meta.Attempts > 0 {
+		c.Ctx.Output.Header("X-Upstream-Attempts", strconv.Itoa(meta.Attempts))
+	}
+	if meta.Language != "" {
+		c.Ctx.Output.Header("Content-Language", meta.Language)
+	}
+	c.Ctx.Output.Header("Vary", "Accept-Language")
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"beego-api-service/requests"
+	"beego-api-service/responses"
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// errorJobNotFound is the error code of an unknown job ID.
+const errorJobNotFound = "job_not_found"
+
+var (
+	propertyJobsOnce sync.Once
+	propertyJobs     *services.JobManager
+	propertyJobsErr  error
+)
+
+// PropertyFetchJobs returns the job manager shared by all requests, loading
+// the jobs saved in jobsDir and resuming the running ones on first use.
+func PropertyFetchJobs() (*services.JobManager, error) {
+	propertyJobsOnce.Do(func() {
+		dir := web.AppConfig.DefaultString("jobsDir", "jobs")
+		workers := web.AppConfig.DefaultInt("jobsConcurrency", 4)
+		retention := time.Duration(web.AppConfig.DefaultInt("jobsRetentionHours", 24)) * time.Hour
+		propertyJobs, propertyJobsErr = services.NewJobManager(dir, workers, retention, fetchJobItem)
+		if propertyJobsErr != nil {
+			log.Printf("failed to load jobs from %s: %v", dir, propertyJobsErr)
+		}
+	})
+	return propertyJobs, propertyJobsErr
+}
+
+// fetchJobItem fetches one property of a job like the bulk endpoints do.
+func fetchJobItem(ctx context.Context, id string, opts services.JobOptions) structs.BulkItem {
+	start := time.Now()
+	var data structs.PropertyDetailsWithProvenance
+	var err error
+	if opts.WithProvenance {
+		data, _, err = services.FetchPropertyDetailsWithProvenance(ctx, id, opts.FetchOptions)
+	} else {
+		data.Details, _, err = services.FetchOSPropertyDetails(ctx, id, opts.FetchOptions)
+	}
+	return bulkItem(id, data, opts.Fields, err, time.Since(start))
+}
+
+type PropertyFetchJobsController struct {
+	web.Controller
+}
+
+// CreateJob starts fetching the properties in the JSON body in the
+// background and answers 202 Accepted with the new job.
+func (c *PropertyFetchJobsController) CreateJob() {
+	request, err := requests.GetPropertyFetchJob(&c.Controller)
+	if err != nil {
+		sendRequestError(&c.Controller, err)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	jobs, err := PropertyFetchJobs()
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	progress, err := jobs.Start(request.IDs, services.JobOptions{
+		FetchOptions:   request.Options,
+		Fields:         request.Fields,
+		WithProvenance: request.WithProvenance,
+	})
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	c.Ctx.Output.Header("Location", "/v1/api/jobs/"+progress.ID)
+	responses.SendJobProgressResponse(&c.Controller, progress, http.StatusAccepted)
+}
+
+func (c *PropertyFetchJobsController) GetJob() {
+	jobId, err := requests.GetJobID(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Job ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := PropertyFetchJobs()
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	progress, err := jobs.Progress(jobId)
+	if err != nil {
+		sendJobError(&c.Controller, err)
+		return
+	}
+	responses.SendJobProgressResponse(&c.Controller, progress, http.StatusOK)
+}
+
+func (c *PropertyFetchJobsController) GetJobResults() {
+	jobId, err := requests.GetJobID(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Job ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	offset, limit, err := requests.GetPage(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorInvalidParameter, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := PropertyFetchJobs()
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	page, err := jobs.Results(jobId, offset, limit)
+	if err != nil {
+		sendJobError(&c.Controller, err)
+		return
+	}
+	responses.SendJobResultsResponse(&c.Controller, page)
+}
+
+// CancelJob stops a running job, keeping the results fetched so far.
+func (c *PropertyFetchJobsController) CancelJob() {
+	jobId, err := requests.GetJobID(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Job ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := PropertyFetchJobs()
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	progress, err := jobs.Cancel(jobId)
+	if err != nil {
+		sendJobError(&c.Controller, err)
+		return
+	}
+	responses.SendJobProgressResponse(&c.Controller, progress, http.StatusOK)
+}
+
+func sendJobError(c *web.Controller, err error) {
+	if errors.Is(err, services.ErrJobNotFound) {
+		responses.SendErrorCodeResponse(c, structs.ErrorResponse{Code: errorJobNotFound, Message: "Job not found"}, http.StatusNotFound)
+		return
+	}
+	sendFetchError(c, err)
+}
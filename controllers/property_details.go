@@ -19,16 +19,80 @@ func (c *PropertyDetailsController) GetPropertyDetails() {
 	propertyId, err := requests.GetPropertyID(&c.Controller)
 	if err != nil {
 		log.Println(err)
-		responses.SendErrorResponse(&c.Controller, "Property ID not provided", http.StatusBadRequest)
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Property ID not provided", http.StatusBadRequest)
 		return
 	}
 
-	transformedData, err := services.FetchPropertyDetails(propertyId)
+	lang, err := requests.GetLanguage(&c.Controller)
 	if err != nil {
 		log.Println(err)
-		responses.SendErrorResponse(&c.Controller, "Failed to fetch property details", http.StatusInternalServerError)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
 		return
 	}
 
+	source, err := requests.GetSource(&c.Controller, services.SourceS3)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedSource, "Unsupported source", http.StatusBadRequest)
+		return
+	}
+
+	withProvenance, err := requests.GetWithProvenance(&c.Controller)
+	if err != nil {
+		responses.SendErrorResponse(&c.Controller, errorInvalidParameter, "Invalid withProvenance value", http.StatusBadRequest)
+		return
+	}
+
+	fields, _, err := requests.GetFields(&c.Controller)
+	if err != nil {
+		sendUnknownFieldsError(&c.Controller, "fields", err)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "detailsDeadlineMs", 10000)
+	defer cancel()
+
+	opts := services.FetchOptions{Language: lang, Source: source}
+	if withProvenance {
+		transformedData, meta, err := services.FetchPropertyDetailsWithProvenance(ctx, propertyId, opts)
+		setFetchHeaders(&c.Controller, meta)
+		if err != nil {
+			sendFetchError(&c.Controller, err)
+			return
+		}
+		if len(fields) > 0 {
+			transformedData.Provenance = fields.SelectProvenance(transformedData.Provenance)
+			sendSelectedFields(&c.Controller, services.FieldSet{"Details": fields, "Provenance": {}}, transformedData)
+			return
+		}
+		responses.SendPropertyDetailsWithProvenanceResponse(&c.Controller, transformedData)
+		return
+	}
+
+	transformedData, meta, err := services.FetchPropertyDetails(ctx, propertyId, opts)
+	setFetchHeaders(&c.Controller, meta)
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	if len(fields) > 0 {
+		sendSelectedFields(&c.Controller, fields, transformedData)
+		return
+	}
 	responses.SendPropertyDetailsResponse(&c.Controller, transformedData)
 }
+
+// sendSelectedFields answers with only the selected fields of data, so that
+// the other fields are never serialized.
+func sendSelectedFields(c *web.Controller, fields services.FieldSet, data interface{}) {
+	projected, err := fields.Project(data)
+	if err != nil {
+		sendFetchError(c, err)
+		return
+	}
+	responses.SendPropertyFieldsResponse(c, projected)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"beego-api-service/requests"
+	"beego-api-service/responses"
+	"beego-api-service/services"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type PropertyFullController struct {
+	web.Controller
+}
+
+func (c *PropertyFullController) GetPropertyFull() {
+	propertyId, err := requests.GetPropertyID(&c.Controller)
+	if err != nil {
+		log.Println(err)
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Property ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	lang, err := requests.GetLanguage(&c.Controller)
+	if err != nil {
+		log.Println(err)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "fullDeadlineMs", 10000)
+	defer cancel()
+
+	transformedData, meta, err := services.FetchPropertyFull(ctx, propertyId, services.FetchOptions{Language: lang})
+	setFetchHeaders(&c.Controller, meta)
+	if err != nil {
+		sendFetchError(&c.Controller, err)
+		return
+	}
+
+	responses.SendPropertyFullResponse(&c.Controller, transformedData)
+}
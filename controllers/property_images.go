@@ -19,14 +19,28 @@ func (c *PropertyImagesController) GetPropertyImages() {
 	propertyId, err := requests.GetPropertyID(&c.Controller)
 	if err != nil {
 		log.Println(err)
-		responses.SendErrorResponse(&c.Controller, "Property ID not provided", http.StatusBadRequest)
+		responses.SendErrorResponse(&c.Controller, errorMissingParameter, "Property ID not provided", http.StatusBadRequest)
 		return
 	}
 
-	transformedData, err := services.FetchPropertyImages(propertyId)
+	lang, err := requests.GetLanguage(&c.Controller)
 	if err != nil {
 		log.Println(err)
-		responses.SendErrorResponse(&c.Controller, "Failed to fetch property images", http.StatusInternalServerError)
+		responses.SendErrorResponse(&c.Controller, errorUnsupportedLanguage, "Unsupported language", http.StatusBadRequest)
+		return
+	}
+
+	if notAcceptable(&c.Controller) {
+		return
+	}
+
+	ctx, cancel := requestContext(&c.Controller, "galleryDeadlineMs", 10000)
+	defer cancel()
+
+	transformedData, meta, err := services.FetchPropertyImages(ctx, propertyId, services.FetchOptions{Language: lang})
+	setFetchHeaders(&c.Controller, meta)
+	if err != nil {
+		sendFetchError(&c.Controller, err)
 		return
 	}
 
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// requestContext returns the context of the incoming request bounded by the
+// endpoint deadline configured under key, in milliseconds. The context is
+// cancelled when the client disconnects.
+func requestContext(c *web.Controller, key string, defaultMs int) (context.Context, context.CancelFunc) {
+	ctx := c.Ctx.Request.Context()
+	if deadline := web.AppConfig.DefaultInt(key, defaultMs); deadline > 0 {
+		return context.WithTimeout(ctx, time.Duration(deadline)*time.Millisecond)
+	}
+	return context.WithCancel(ctx)
+}
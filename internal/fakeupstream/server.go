@@ -0,0 +1,246 @@
+// Package fakeupstream imitates the dynamodb-s3-os API for cmd/fakeupstream
+// and for tests, serving property documents from a fixtures directory and
+// injecting the failures a Scenario asks for.
+package fakeupstream
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScenarioPath is where integration tests read and replace the scenario of a
+// running server.
+const ScenarioPath = "/__scenario"
+
+// FixturesDir is the absolute path of the fixtures shipped with
+// cmd/fakeupstream, so tests of any package can serve them.
+func FixturesDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "cmd", "fakeupstream", "fixtures")
+}
+
+// Scenario configures the misbehaviour the fake upstream injects.
+type Scenario struct {
+	// LatencyMs delays every response; JitterMs adds a random extra delay.
+	LatencyMs int `json:"latencyMs"`
+	JitterMs  int `json:"jitterMs"`
+	// ErrorRate is the fraction of requests answered with ErrorStatus.
+	ErrorRate   float64 `json:"errorRate"`
+	ErrorStatus int     `json:"errorStatus"`
+	// FailFirst answers the first requests for each property with
+	// ErrorStatus, whatever ErrorRate draws.
+	FailFirst int `json:"failFirst"`
+	// RetryAfterSeconds is sent with injected errors when set.
+	RetryAfterSeconds int `json:"retryAfterSeconds"`
+	// TruncateRate is the fraction of documents cut off half way.
+	TruncateRate float64 `json:"truncateRate"`
+	// NotFoundIDs are answered with 404 even if a fixture exists.
+	NotFoundIDs []string `json:"notFoundIds"`
+	// Properties overrides the scenario for single property IDs.
+	Properties map[string]Scenario `json:"properties,omitempty"`
+}
+
+// forProperty returns the scenario that applies to propertyId.
+func (s Scenario) forProperty(propertyId string) Scenario {
+	if override, ok := s.Properties[propertyId]; ok {
+		override.NotFoundIDs = append(override.NotFoundIDs, s.NotFoundIDs...)
+		return override
+	}
+	return s
+}
+
+func (s Scenario) notFound(propertyId string) bool {
+	for _, id := range s.NotFoundIDs {
+		if id == propertyId {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadScenario reads a scenario from a JSON file.
+func LoadScenario(path string) (Scenario, error) {
+	var scenario Scenario
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+	err = json.Unmarshal(data, &scenario)
+	return scenario, err
+}
+
+// Server imitates the dynamodb-s3-os API. Documents are read from
+// <fixturesDir>/<propertyId>/<languageCode>.json, the layout written by the
+// service's record mode.
+type Server struct {
+	fixturesDir string
+
+	mu       sync.Mutex
+	scenario Scenario
+	rand     *rand.Rand
+	sleep    func(r *http.Request, d time.Duration)
+	// requests counts the document requests per property ID.
+	requests map[string]int
+}
+
+// New returns a server of the documents in fixturesDir. seed makes the
+// injected randomness repeatable.
+func New(fixturesDir string, scenario Scenario, seed int64) *Server {
+	return &Server{
+		fixturesDir: fixturesDir,
+		scenario:    scenario,
+		rand:        rand.New(rand.NewSource(seed)),
+		sleep:       sleepContext,
+		requests:    map[string]int{},
+	}
+}
+
+// Requests returns how many document requests were received, failed ones
+// included.
+func (f *Server) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	total := 0
+	for _, n := range f.requests {
+		total += n
+	}
+	return total
+}
+
+func (f *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ScenarioPath {
+		f.serveScenario(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	propertyId := r.URL.Query().Get("propertyId")
+	languageCode := r.URL.Query().Get("languageCode")
+	if languageCode == "" {
+		languageCode = "en"
+	}
+	if propertyId == "" {
+		writeJSONError(w, http.StatusBadRequest, "Invalid property ID")
+		return
+	}
+	f.mu.Lock()
+	f.requests[propertyId]++
+	f.mu.Unlock()
+
+	scenario, delay, injectError, truncate := f.roll(propertyId)
+	if delay > 0 {
+		f.sleep(r, delay)
+	}
+
+	if scenario.notFound(propertyId) {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if injectError {
+		status := scenario.ErrorStatus
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		if scenario.RetryAfterSeconds > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(scenario.RetryAfterSeconds))
+		}
+		writeJSONError(w, status, "injected failure")
+		return
+	}
+
+	body, err := f.readFixture(propertyId, languageCode)
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		log.Printf("failed to read fixture for %s/%s: %v", propertyId, languageCode, err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to read fixture")
+		return
+	}
+
+	if truncate {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// roll picks the scenario for a request and draws its random outcomes.
+func (f *Server) roll(propertyId string) (Scenario, time.Duration, bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	scenario := f.scenario.forProperty(propertyId)
+	delay := time.Duration(scenario.LatencyMs) * time.Millisecond
+	if scenario.JitterMs > 0 {
+		delay += time.Duration(f.rand.Intn(scenario.JitterMs+1)) * time.Millisecond
+	}
+	injectError := f.requests[propertyId] <= scenario.FailFirst ||
+		scenario.ErrorRate > 0 && f.rand.Float64() < scenario.ErrorRate
+	truncate := scenario.TruncateRate > 0 && f.rand.Float64() < scenario.TruncateRate
+	return scenario, delay, injectError, truncate
+}
+
+// serveScenario returns the current scenario on GET and replaces it on PUT.
+func (f *Server) serveScenario(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var scenario Scenario
+		if err := json.NewDecoder(r.Body).Decode(&scenario); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid scenario: "+err.Error())
+			return
+		}
+		f.mu.Lock()
+		f.scenario = scenario
+		f.mu.Unlock()
+		log.Printf("scenario replaced: %+v", scenario)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	f.mu.Lock()
+	scenario := f.scenario
+	f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scenario)
+}
+
+func (f *Server) readFixture(propertyId, languageCode string) ([]byte, error) {
+	id, lang := url.PathEscape(propertyId), url.PathEscape(languageCode)
+	if id == "." || id == ".." || lang == "." || lang == ".." {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(f.fixturesDir, id, lang+".json"))
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// sleepContext waits for d or until the client goes away.
+func sleepContext(r *http.Request, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,144 @@
+package fakeupstream
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fixtureID = "HA-3213808988"
+
+func TestFakeUpstream(t *testing.T) {
+	tests := []struct {
+		name           string
+		scenario       Scenario
+		query          string
+		expectedStatus int
+		expectedDelay  time.Duration
+		validateBody   func(*testing.T, string)
+	}{
+		{
+			name:           "Serves fixture",
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusOK,
+			validateBody: func(t *testing.T, body string) {
+				var document map[string]interface{}
+				assert.NoError(t, json.Unmarshal([]byte(body), &document))
+				assert.Contains(t, document, "S3")
+				assert.Contains(t, document, "OS")
+				assert.Contains(t, document, "S3-Gallery")
+			},
+		},
+		{
+			name:           "Missing property ID",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing fixture",
+			query:          "?propertyId=" + fixtureID + "&languageCode=fr",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Path traversal",
+			query:          "?propertyId=..&languageCode=en",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Not found ID",
+			scenario:       Scenario{NotFoundIDs: []string{fixtureID}},
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Injected error",
+			scenario:       Scenario{ErrorRate: 1, ErrorStatus: http.StatusBadGateway},
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			name:           "First requests fail",
+			scenario:       Scenario{FailFirst: 1, ErrorStatus: http.StatusTooManyRequests},
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "Truncated body",
+			scenario:       Scenario{TruncateRate: 1},
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusOK,
+			validateBody: func(t *testing.T, body string) {
+				var document map[string]interface{}
+				assert.Error(t, json.Unmarshal([]byte(body), &document))
+			},
+		},
+		{
+			name:           "Per-property latency",
+			scenario:       Scenario{LatencyMs: 10, Properties: map[string]Scenario{fixtureID: {LatencyMs: 500}}},
+			query:          "?propertyId=" + fixtureID + "&languageCode=en",
+			expectedStatus: http.StatusOK,
+			expectedDelay:  500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := New(FixturesDir(), tt.scenario, 1)
+			var slept time.Duration
+			upstream.sleep = func(r *http.Request, d time.Duration) { slept += d }
+
+			w := httptest.NewRecorder()
+			upstream.ServeHTTP(w, httptest.NewRequest("GET", "/dynamodb-s3-os"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedDelay, slept)
+			if tt.validateBody != nil {
+				tt.validateBody(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFakeUpstreamScenarioEndpoint(t *testing.T) {
+	upstream := New(FixturesDir(), Scenario{}, 1)
+
+	w := httptest.NewRecorder()
+	upstream.ServeHTTP(w, httptest.NewRequest("PUT", ScenarioPath, strings.NewReader(`{"notFoundIds": ["`+fixtureID+`"]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	upstream.ServeHTTP(w, httptest.NewRequest("GET", "/dynamodb-s3-os?propertyId="+fixtureID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	upstream.ServeHTTP(w, httptest.NewRequest("PUT", ScenarioPath, strings.NewReader(`not json`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFakeUpstreamFailFirst(t *testing.T) {
+	upstream := New(FixturesDir(), Scenario{FailFirst: 2}, 1)
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		upstream.ServeHTTP(w, httptest.NewRequest("GET", "/dynamodb-s3-os?propertyId="+fixtureID, nil))
+		statuses = append(statuses, w.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, statuses)
+	assert.Equal(t, 3, upstream.Requests())
+}
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario(filepath.Join(FixturesDir(), "..", "scenarios", "flaky.json"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.2, scenario.ErrorRate)
+	assert.Equal(t, []string{"HA-404"}, scenario.NotFoundIDs)
+}
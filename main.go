@@ -1,11 +1,14 @@
 package main
 
 import (
+	"beego-api-service/controllers"
 	_ "beego-api-service/routers"
 	beego "github.com/beego/beego/v2/server/web"
 )
 
 func main() {
+	// Resume the jobs that were running when the service stopped
+	controllers.PropertyFetchJobs()
 	beego.Run()
 }
 
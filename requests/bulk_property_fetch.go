@@ -1,19 +1,211 @@
 package requests
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"strings"
 
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
 	"github.com/beego/beego/v2/server/web"
 )
 
+// ErrTooManyPropertyIDs is returned when a request asks for more properties
+// than bulkMaxIDs allows.
+var ErrTooManyPropertyIDs = errors.New("too many property IDs")
+
+// ErrRequestBodyTooLarge is returned when a JSON body is larger than
+// maxBulkRequestBytes.
+var ErrRequestBodyTooLarge = fmt.Errorf("request body too large, at most %d bytes are allowed", maxBulkRequestBytes)
+
+// GetPropertyIDs reads the comma-separated ?propertyIds= parameter. IDs are
+// normalized like the IDs of GetBulkPropertyFetch: trimmed, with duplicates
+// dropped and blank entries reported as an InvalidRequestError.
 func GetPropertyIDs(c *web.Controller) ([]string, error) {
 	propertyIds := c.GetString("propertyIds")
 	if propertyIds == "" {
 		log.Printf("no property IDs provided")
 		return nil, errors.New("no property IDs provided")
 	}
-	ids := strings.Split(propertyIds, ",")
+	ids, invalid := normalizePropertyIDs(strings.Split(propertyIds, ","), "propertyIds")
+	if len(invalid) > 0 {
+		err := &InvalidRequestError{Message: "invalid propertyIds parameter", Fields: invalid}
+		log.Println(err)
+		return nil, err
+	}
+	if err := checkPropertyIDCount(len(ids), MaxPropertyIDs()); err != nil {
+		return nil, err
+	}
 	return ids, nil
 }
+
+// normalizePropertyIDs trims each ID and drops duplicates, keeping the first
+// occurrence. Blank IDs are reported by their index under path.
+func normalizePropertyIDs(raw []string, path string) ([]string, []structs.FieldError) {
+	var ids []string
+	var invalid []structs.FieldError
+	seen := make(map[string]bool, len(raw))
+	for i, id := range raw {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			invalid = append(invalid, structs.FieldError{Path: fmt.Sprintf("%s[%d]", path, i), Reason: "empty property ID"})
+			continue
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, invalid
+}
+
+// MaxPropertyIDs is the most property IDs a single request may ask for, or 0
+// when bulkMaxIDs is not set and any number is accepted.
+func MaxPropertyIDs() int {
+	return web.AppConfig.DefaultInt("bulkMaxIDs", 0)
+}
+
+func checkPropertyIDCount(count, max int) error {
+	if max > 0 && count > max {
+		log.Printf("too many property IDs: %d > %d", count, max)
+		return fmt.Errorf("%w: %d > %d", ErrTooManyPropertyIDs, count, max)
+	}
+	return nil
+}
+
+// maxBulkRequestBytes bounds the JSON body of bulk and job requests, enough
+// for tens of thousands of property IDs.
+const maxBulkRequestBytes = 8 << 20
+
+// BulkPropertyFetch is a validated POST /propertyList request.
+type BulkPropertyFetch struct {
+	IDs     []string
+	Options services.FetchOptions
+	Fields  services.FieldSet
+	// FieldPaths are the selected fields as given, in order.
+	FieldPaths     []string
+	WithProvenance bool
+}
+
+// InvalidRequestError is returned when a request body cannot be used. Fields
+// lists every offending entry.
+type InvalidRequestError struct {
+	Message string
+	Fields  []structs.FieldError
+}
+
+func (e *InvalidRequestError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.Path + ": " + field.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(reasons, "; "))
+}
+
+// GetBulkPropertyFetch reads the JSON body of POST /propertyList. IDs are
+// trimmed and duplicates dropped, keeping the first occurrence. Blank IDs,
+// too many IDs, an unknown source, language or field are all reported
+// together. Without a Language the ?lang= parameter and Accept-Language
+// header are used, as for GET.
+func GetBulkPropertyFetch(c *web.Controller) (BulkPropertyFetch, error) {
+	return getBulkPropertyFetch(c, MaxPropertyIDs())
+}
+
+// GetPropertyFetchJob reads the JSON body of POST /jobs/property-fetch. It is
+// validated like GetBulkPropertyFetch, with jobsMaxIDs as the maximum.
+func GetPropertyFetchJob(c *web.Controller) (BulkPropertyFetch, error) {
+	return getBulkPropertyFetch(c, web.AppConfig.DefaultInt("jobsMaxIDs", 50000))
+}
+
+func getBulkPropertyFetch(c *web.Controller, maxIDs int) (BulkPropertyFetch, error) {
+	var body structs.BulkPropertyFetchRequest
+	if err := decodeJSONBody(c, &body); err != nil {
+		log.Printf("invalid bulk request body: %v", err)
+		if errors.Is(err, ErrRequestBodyTooLarge) {
+			return BulkPropertyFetch{}, err
+		}
+		return BulkPropertyFetch{}, &InvalidRequestError{Message: "invalid JSON body: " + err.Error()}
+	}
+
+	var request BulkPropertyFetch
+	var invalid []structs.FieldError
+	request.IDs, invalid = normalizePropertyIDs(body.IDs, "IDs")
+	if len(body.IDs) == 0 {
+		invalid = append(invalid, structs.FieldError{Path: "IDs", Reason: "no property IDs provided"})
+	} else if err := checkPropertyIDCount(len(request.IDs), maxIDs); err != nil {
+		invalid = append(invalid, structs.FieldError{Path: "IDs", Reason: fmt.Sprintf("at most %d property IDs are allowed, got %d", maxIDs, len(request.IDs))})
+	}
+
+	source, err := services.ParseSource(body.Source, services.SourceOS)
+	if err != nil {
+		invalid = append(invalid, structs.FieldError{Path: "Source", Reason: err.Error()})
+	}
+	request.Options.Source = source
+
+	if lang := strings.TrimSpace(body.Language); lang != "" {
+		request.Options.Language = matchLanguage(lang, SupportedLanguages())
+		if request.Options.Language == "" {
+			invalid = append(invalid, structs.FieldError{Path: "Language", Reason: "unsupported language: " + lang})
+		}
+	} else if request.Options.Language, err = GetLanguage(c); err != nil {
+		invalid = append(invalid, structs.FieldError{Path: "Language", Reason: err.Error()})
+	}
+
+	for i, field := range body.Fields {
+		if _, err := services.ParseFields([]string{field}, structs.PropertyDetailsResponse{}); err != nil {
+			invalid = append(invalid, structs.FieldError{Path: fmt.Sprintf("Fields[%d]", i), Reason: "unknown field: " + field})
+		}
+	}
+	request.Fields, _ = services.ParseFields(body.Fields, structs.PropertyDetailsResponse{})
+	for _, field := range body.Fields {
+		if field = strings.TrimSpace(field); field != "" {
+			request.FieldPaths = append(request.FieldPaths, field)
+		}
+	}
+	request.WithProvenance = body.WithProvenance
+
+	if len(invalid) > 0 {
+		err := &InvalidRequestError{Message: "invalid bulk request", Fields: invalid}
+		log.Println(err)
+		return BulkPropertyFetch{}, err
+	}
+	return request, nil
+}
+
+// decodeJSONBody decodes the request body into v, rejecting unknown keys and
+// trailing data. A body larger than maxBulkRequestBytes is rejected with
+// ErrRequestBodyTooLarge rather than decoded truncated.
+func decodeJSONBody(c *web.Controller, v interface{}) error {
+	body := c.Ctx.Input.RequestBody
+	if len(body) == 0 && c.Ctx.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(c.Ctx.Request.Body, maxBulkRequestBytes+1)); err != nil {
+			return err
+		}
+	}
+	if len(body) > maxBulkRequestBytes {
+		return ErrRequestBodyTooLarge
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("empty body")
+		}
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after JSON object")
+	}
+	return nil
+}
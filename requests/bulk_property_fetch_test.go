@@ -1,16 +1,25 @@
 package requests
 
 import (
+	"errors"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
 	"github.com/beego/beego/v2/server/web"
 	"github.com/beego/beego/v2/server/web/context"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetPropertyIDs(t *testing.T) {
+	web.AppConfig.Set("bulkMaxIDs", "3")
+	defer web.AppConfig.Set("bulkMaxIDs", "")
+
 	tests := []struct {
 		name        string
 		propertyIds string
@@ -40,12 +49,33 @@ func TestGetPropertyIDs(t *testing.T) {
 			errorMsg:    "no property IDs provided",
 		},
 		{
-			name:        "handles whitespace",
+			name:        "trims whitespace",
 			propertyIds: "123, 456, 789",
-			want:        []string{"123", " 456", " 789"},
+			want:        []string{"123", "456", "789"},
 			wantErr:     false,
 			errorMsg:    "",
 		},
+		{
+			name:        "duplicates do not count towards the maximum",
+			propertyIds: "1,2,3, 3,1",
+			want:        []string{"1", "2", "3"},
+			wantErr:     false,
+			errorMsg:    "",
+		},
+		{
+			name:        "blank entries",
+			propertyIds: "1,,2, ",
+			want:        nil,
+			wantErr:     true,
+			errorMsg:    "invalid propertyIds parameter: propertyIds[1]: empty property ID; propertyIds[3]: empty property ID",
+		},
+		{
+			name:        "too many IDs",
+			propertyIds: "1,2,3,4",
+			want:        nil,
+			wantErr:     true,
+			errorMsg:    "too many property IDs: 4 > 3",
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,3 +117,137 @@ func TestGetPropertyIDs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPropertyIDsNoLimitByDefault(t *testing.T) {
+	ids := make([]string, 500)
+	for i := range ids {
+		ids[i] = "HA-" + strconv.Itoa(i)
+	}
+	req := httptest.NewRequest("GET", "/test?propertyIds="+strings.Join(ids, ","), nil)
+	ctx := context.NewContext()
+	ctx.Reset(httptest.NewRecorder(), req)
+	ctrl := &web.Controller{}
+	ctrl.Init(ctx, "", "", nil)
+
+	got, err := GetPropertyIDs(ctrl)
+
+	assert.Equal(t, 0, MaxPropertyIDs())
+	assert.NoError(t, err)
+	assert.Equal(t, ids, got)
+}
+
+func TestGetBulkPropertyFetch(t *testing.T) {
+	web.AppConfig.Set("bulkMaxIDs", "3")
+	web.AppConfig.Set("supportedLanguages", "en;fr")
+	defer web.AppConfig.Set("bulkMaxIDs", "")
+	defer web.AppConfig.Set("supportedLanguages", "")
+
+	tests := []struct {
+		name        string
+		body        string
+		want        BulkPropertyFetch
+		wantMessage string
+		wantFields  []structs.FieldError
+	}{
+		{
+			name: "trims and deduplicates IDs",
+			body: `{"IDs": [" HA-1", "HA-2 ", "HA-1"], "Source": "merged", "Language": "fr-CA", "Fields": ["ID", "Property.Price"], "WithProvenance": true}`,
+			want: BulkPropertyFetch{
+				IDs:            []string{"HA-1", "HA-2"},
+				Options:        services.FetchOptions{Language: "fr", Source: services.SourceMerged},
+				Fields:         services.FieldSet{"ID": services.FieldSet{}, "Property": services.FieldSet{"Price": services.FieldSet{}}},
+				FieldPaths:     []string{"ID", "Property.Price"},
+				WithProvenance: true,
+			},
+		},
+		{
+			name: "defaults",
+			body: `{"ids": ["HA-1"]}`,
+			want: BulkPropertyFetch{
+				IDs:     []string{"HA-1"},
+				Options: services.FetchOptions{Language: "en", Source: services.SourceOS},
+				Fields:  services.FieldSet{},
+			},
+		},
+		{
+			name: "duplicates do not count towards the maximum",
+			body: `{"IDs": ["HA-1", "HA-2", "HA-3", "HA-3"]}`,
+			want: BulkPropertyFetch{
+				IDs:     []string{"HA-1", "HA-2", "HA-3"},
+				Options: services.FetchOptions{Language: "en", Source: services.SourceOS},
+				Fields:  services.FieldSet{},
+			},
+		},
+		{
+			name:        "lists every offending entry",
+			body:        `{"IDs": ["HA-1", " ", "HA-2", "HA-3", "HA-4"], "Source": "dynamo", "Language": "es", "Fields": ["ID", "Nope"]}`,
+			wantMessage: "invalid bulk request",
+			wantFields: []structs.FieldError{
+				{Path: "IDs[1]", Reason: "empty property ID"},
+				{Path: "IDs", Reason: "at most 3 property IDs are allowed, got 4"},
+				{Path: "Source", Reason: "unsupported source: dynamo"},
+				{Path: "Language", Reason: "unsupported language: es"},
+				{Path: "Fields[1]", Reason: "unknown field: Nope"},
+			},
+		},
+		{
+			name:        "no IDs",
+			body:        `{"IDs": []}`,
+			wantMessage: "invalid bulk request",
+			wantFields:  []structs.FieldError{{Path: "IDs", Reason: "no property IDs provided"}},
+		},
+		{
+			name:        "empty body",
+			body:        ``,
+			wantMessage: "invalid JSON body: empty body",
+		},
+		{
+			name:        "unknown key",
+			body:        `{"IDs": ["HA-1"], "Limit": 3}`,
+			wantMessage: `invalid JSON body: json: unknown field "Limit"`,
+		},
+		{
+			name:        "wrong type",
+			body:        `{"IDs": "HA-1,HA-2"}`,
+			wantMessage: "invalid JSON body: json: cannot unmarshal string into Go struct field BulkPropertyFetchRequest.IDs of type []string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/test", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			ctx := context.NewContext()
+			ctx.Reset(w, req)
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetBulkPropertyFetch(ctrl)
+
+			if tt.wantMessage != "" {
+				var invalidErr *InvalidRequestError
+				assert.True(t, errors.As(err, &invalidErr))
+				assert.Equal(t, tt.wantMessage, invalidErr.Message)
+				assert.Equal(t, tt.wantFields, invalidErr.Fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetBulkPropertyFetchBodyTooLarge(t *testing.T) {
+	body := `{"IDs": ["HA-1"], "Source": "` + strings.Repeat("x", maxBulkRequestBytes) + `"}`
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+	ctx := context.NewContext()
+	ctx.Reset(httptest.NewRecorder(), req)
+	ctrl := &web.Controller{}
+	ctrl.Init(ctx, "", "", nil)
+
+	_, err := GetBulkPropertyFetch(ctrl)
+
+	assert.ErrorIs(t, err, ErrRequestBodyTooLarge)
+}
@@ -0,0 +1,84 @@
+package requests
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"beego-api-service/responses"
+	"beego-api-service/services"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// bulkFormats are the values of ?format= on the bulk endpoints and the media
+// types they select. JSON is "".
+var bulkFormats = map[string]string{
+	"json":   "",
+	"ndjson": responses.MediaTypeNDJSON,
+	"sse":    responses.MediaTypeEventStream,
+	"csv":    responses.MediaTypeCSV,
+	"xlsx":   responses.MediaTypeXLSX,
+}
+
+// GetBulkMediaType picks the format of a bulk response: the ?format=
+// parameter if set, otherwise the media type the Accept header prefers among
+// NDJSON, server-sent events, CSV and XLSX. It returns "" for a buffered
+// response, whose encoding responses negotiates. These formats must be asked
+// for by name; wildcards select a buffered response.
+func GetBulkMediaType(c *web.Controller) (string, error) {
+	if format := strings.TrimSpace(c.GetString("format")); format != "" {
+		mediaType, ok := bulkFormats[strings.ToLower(format)]
+		if !ok {
+			log.Printf("unsupported format requested: %s", format)
+			return "", fmt.Errorf("unsupported format: %s", format)
+		}
+		return mediaType, nil
+	}
+
+	for _, mediaType := range parseQualityList(c.Ctx.Input.Header("Accept")) {
+		switch mediaType = strings.ToLower(mediaType); mediaType {
+		case responses.MediaTypeNDJSON, responses.MediaTypeEventStream, responses.MediaTypeCSV, responses.MediaTypeXLSX:
+			return mediaType, nil
+		}
+		if _, ok := responses.NegotiateMediaType(mediaType); ok {
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// GetExportColumns reads the ?columns= parameter of a CSV or XLSX export, a
+// comma-separated list of "path" or "path:Header". Without it, defaultColumns
+// are used, and without those every column.
+func GetExportColumns(c *web.Controller, defaultColumns []string) ([]services.ExportColumn, error) {
+	specs := defaultColumns
+	if columns := c.GetString("columns"); columns != "" {
+		specs = strings.Split(columns, ",")
+	}
+	columns, err := services.ParseExportColumns(specs)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return columns, nil
+}
+
+// GetFields reads the optional ?fields= parameter, a comma-separated list of
+// dotted paths into the property details such as Property.Price. It returns
+// the parsed fields and the paths as given; no fields selects everything.
+func GetFields(c *web.Controller) (services.FieldSet, []string, error) {
+	var paths []string
+	for _, path := range strings.Split(c.GetString("fields"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	fields, err := services.ParseFields(paths, structs.PropertyDetailsResponse{})
+	if err != nil {
+		log.Println(err)
+		return nil, nil, err
+	}
+	return fields, paths, nil
+}
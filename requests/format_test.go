@@ -0,0 +1,211 @@
+package requests
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/responses"
+	"beego-api-service/services"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBulkMediaType(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		accept  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no Accept header",
+			want: "",
+		},
+		{
+			name:   "NDJSON",
+			accept: "application/x-ndjson",
+			want:   responses.MediaTypeNDJSON,
+		},
+		{
+			name:   "server-sent events with parameters",
+			accept: "Text/Event-Stream; charset=utf-8",
+			want:   responses.MediaTypeEventStream,
+		},
+		{
+			name:   "JSON preferred",
+			accept: "application/x-ndjson;q=0.5, application/json",
+			want:   "",
+		},
+		{
+			name:   "streaming preferred",
+			accept: "application/json;q=0.5, application/x-ndjson",
+			want:   responses.MediaTypeNDJSON,
+		},
+		{
+			name:   "wildcard",
+			accept: "*/*",
+			want:   "",
+		},
+		{
+			name:   "XML preferred",
+			accept: "text/csv;q=0.5, application/xml",
+			want:   "",
+		},
+		{
+			name:   "refused stream",
+			accept: "text/event-stream;q=0",
+			want:   "",
+		},
+		{
+			name:   "CSV",
+			accept: "text/csv",
+			want:   responses.MediaTypeCSV,
+		},
+		{
+			name:   "XLSX",
+			accept: responses.MediaTypeXLSX,
+			want:   responses.MediaTypeXLSX,
+		},
+		{
+			name:   "format parameter wins over header",
+			query:  "?format=XLSX",
+			accept: "text/csv",
+			want:   responses.MediaTypeXLSX,
+		},
+		{
+			name:  "JSON format parameter",
+			query: "?format=json",
+			want:  "",
+		},
+		{
+			name:    "unsupported format parameter",
+			query:   "?format=pdf",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			ctx := context.NewContext()
+			ctx.Reset(w, req)
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetBulkMediaType(ctrl)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetExportColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		defaults []string
+		want     []services.ExportColumn
+		wantErr  bool
+	}{
+		{
+			name:     "columns parameter wins over defaults",
+			query:    "?columns=ID,GeoInfo.City:City",
+			defaults: []string{"Partner"},
+			want:     []services.ExportColumn{{Path: "ID", Header: "ID"}, {Path: "GeoInfo.City", Header: "City"}},
+		},
+		{
+			name:     "defaults",
+			defaults: []string{"Partner.OwnerID"},
+			want:     []services.ExportColumn{{Path: "Partner.OwnerID", Header: "Partner.OwnerID"}},
+		},
+		{
+			name:    "unknown column",
+			query:   "?columns=ID,Nope",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test"+tt.query, nil))
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetExportColumns(ctrl, tt.defaults)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      services.FieldSet
+		wantPaths []string
+		wantErr   []string
+	}{
+		{
+			name: "no fields",
+			want: services.FieldSet{},
+		},
+		{
+			name:      "dotted paths",
+			query:     "?fields=ID,%20Property.PropertyName,Property.Price,,GeoInfo.City",
+			want:      services.FieldSet{"ID": {}, "Property": {"PropertyName": {}, "Price": {}}, "GeoInfo": {"City": {}}},
+			wantPaths: []string{"ID", "Property.PropertyName", "Property.Price", "GeoInfo.City"},
+		},
+		{
+			name:    "unknown paths",
+			query:   "?fields=ID,Property.Prices,City",
+			wantErr: []string{"Property.Prices", "City"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test"+tt.query, nil))
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, paths, err := GetFields(ctrl)
+
+			if tt.wantErr != nil {
+				var unknownErr *services.UnknownFieldsError
+				assert.True(t, errors.As(err, &unknownErr))
+				assert.Equal(t, tt.wantErr, unknownErr.Fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+func GetJobID(c *web.Controller) (string, error) {
+	jobId := c.Ctx.Input.Param(":id")
+	if jobId == "" {
+		log.Printf("job ID not provided")
+		return "", errors.New("job ID not provided")
+	}
+	return jobId, nil
+}
+
+// GetPage reads the optional ?offset= and ?limit= query parameters. The limit
+// defaults to 100 and may not exceed jobsMaxPageSize (default 1000).
+func GetPage(c *web.Controller) (offset, limit int, err error) {
+	maxLimit := web.AppConfig.DefaultInt("jobsMaxPageSize", 1000)
+
+	if offset, err = getNonNegativeInt(c, "offset", 0); err != nil {
+		return 0, 0, err
+	}
+	if limit, err = getNonNegativeInt(c, "limit", 100); err != nil {
+		return 0, 0, err
+	}
+	if limit == 0 || limit > maxLimit {
+		log.Printf("invalid limit value: %d", limit)
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return offset, limit, nil
+}
+
+func getNonNegativeInt(c *web.Controller, key string, defaultValue int) (int, error) {
+	value := c.GetString(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Printf("invalid %s value: %s", key, value)
+		return 0, fmt.Errorf("invalid %s value: %s", key, value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,76 @@
+package requests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPage(t *testing.T) {
+	web.AppConfig.Set("jobsMaxPageSize", "500")
+	defer web.AppConfig.Set("jobsMaxPageSize", "1000")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantOffset int
+		wantLimit  int
+		errorMsg   string
+	}{
+		{
+			name:      "defaults",
+			wantLimit: 100,
+		},
+		{
+			name:       "offset and limit",
+			query:      "?offset=200&limit=50",
+			wantOffset: 200,
+			wantLimit:  50,
+		},
+		{
+			name:     "negative offset",
+			query:    "?offset=-1",
+			errorMsg: "invalid offset value: -1",
+		},
+		{
+			name:     "limit not a number",
+			query:    "?limit=all",
+			errorMsg: "invalid limit value: all",
+		},
+		{
+			name:     "limit too large",
+			query:    "?limit=501",
+			errorMsg: "limit must be between 1 and 500",
+		},
+		{
+			name:     "zero limit",
+			query:    "?limit=0",
+			errorMsg: "limit must be between 1 and 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test"+tt.query, nil))
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			offset, limit, err := GetPage(ctrl)
+
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.errorMsg, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOffset, offset)
+			assert.Equal(t, tt.wantLimit, limit)
+		})
+	}
+}
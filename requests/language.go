@@ -0,0 +1,104 @@
+package requests
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// GetLanguage picks the language to serve from the ?lang= query parameter or,
+// failing that, the Accept-Language header. An explicit ?lang= that is not in
+// supportedLanguages is an error; an unmatched Accept-Language falls back to
+// defaultLanguage.
+func GetLanguage(c *web.Controller) (string, error) {
+	supported := SupportedLanguages()
+	defaultLanguage := web.AppConfig.DefaultString("defaultLanguage", "en")
+
+	if lang := strings.TrimSpace(c.GetString("lang")); lang != "" {
+		if match := matchLanguage(lang, supported); match != "" {
+			return match, nil
+		}
+		log.Printf("unsupported language requested: %s", lang)
+		return "", fmt.Errorf("unsupported language: %s", lang)
+	}
+
+	for _, lang := range parseAcceptLanguage(c.Ctx.Input.Header("Accept-Language")) {
+		if lang == "*" {
+			return defaultLanguage, nil
+		}
+		if match := matchLanguage(lang, supported); match != "" {
+			return match, nil
+		}
+	}
+
+	return defaultLanguage, nil
+}
+
+// SupportedLanguages returns the languages configured in supportedLanguages,
+// separated by ";". Only the default language is supported when unset.
+func SupportedLanguages() []string {
+	return web.AppConfig.DefaultStrings("supportedLanguages", []string{web.AppConfig.DefaultString("defaultLanguage", "en")})
+}
+
+// matchLanguage finds lang in supported, first exactly and then by its
+// primary subtag, so that "fr-CA" matches a supported "fr".
+func matchLanguage(lang string, supported []string) string {
+	for _, s := range supported {
+		if strings.EqualFold(strings.TrimSpace(s), lang) {
+			return strings.TrimSpace(s)
+		}
+	}
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		return matchLanguage(base, supported)
+	}
+	return ""
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header
+// ordered by their quality values.
+func parseAcceptLanguage(header string) []string {
+	return parseQualityList(header)
+}
+
+// parseQualityList returns the values of a comma-separated header such as
+// Accept or Accept-Language ordered by their quality values, dropping their
+// other parameters and any value with q=0.
+func parseQualityList(header string) []string {
+	type weighted struct {
+		value   string
+		quality float64
+	}
+
+	var values []weighted
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		if quality > 0 {
+			values = append(values, weighted{value: value, quality: quality})
+		}
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].quality > values[j].quality
+	})
+
+	ordered := make([]string, len(values))
+	for i, value := range values {
+		ordered[i] = value.value
+	}
+	return ordered
+}
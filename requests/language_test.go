@@ -0,0 +1,84 @@
+package requests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLanguage(t *testing.T) {
+	web.AppConfig.Set("supportedLanguages", "en;fr;de-AT")
+	defer web.AppConfig.Set("supportedLanguages", "")
+
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		want           string
+		wantErr        bool
+	}{
+		{
+			name: "defaults to English",
+			want: "en",
+		},
+		{
+			name:  "query parameter",
+			query: "?lang=fr",
+			want:  "fr",
+		},
+		{
+			name:           "query parameter wins over header",
+			query:          "?lang=fr",
+			acceptLanguage: "de-AT",
+			want:           "fr",
+		},
+		{
+			name:    "unsupported query parameter",
+			query:   "?lang=es",
+			wantErr: true,
+		},
+		{
+			name:           "header ordered by quality",
+			acceptLanguage: "es;q=0.9, de-AT;q=0.5, fr;q=0.8",
+			want:           "fr",
+		},
+		{
+			name:           "regional variant matches base language",
+			acceptLanguage: "fr-CA",
+			want:           "fr",
+		},
+		{
+			name:           "unsupported header falls back to default",
+			acceptLanguage: "es, it;q=0.5",
+			want:           "en",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+
+			ctx := context.NewContext()
+			ctx.Reset(w, req)
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetLanguage(ctrl)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
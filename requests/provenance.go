@@ -0,0 +1,23 @@
+package requests
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// GetWithProvenance reads the optional ?withProvenance= flag.
+func GetWithProvenance(c *web.Controller) (bool, error) {
+	value := c.GetString("withProvenance")
+	if value == "" {
+		return false, nil
+	}
+	withProvenance, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("invalid withProvenance value: %s", value)
+		return false, fmt.Errorf("invalid withProvenance value: %s", value)
+	}
+	return withProvenance, nil
+}
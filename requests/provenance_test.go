@@ -0,0 +1,59 @@
+package requests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWithProvenance(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "absent",
+			want: false,
+		},
+		{
+			name:  "true",
+			query: "?withProvenance=true",
+			want:  true,
+		},
+		{
+			name:  "false",
+			query: "?withProvenance=0",
+			want:  false,
+		},
+		{
+			name:    "invalid",
+			query:   "?withProvenance=yes-please",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test"+tt.query, nil))
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetWithProvenance(ctrl)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package requests
+
+import (
+	"log"
+
+	"beego-api-service/services"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// GetSource reads the ?source= query parameter (s3, os or merged), returning
+// defaultSource when it is absent.
+func GetSource(c *web.Controller, defaultSource services.Source) (services.Source, error) {
+	source, err := services.ParseSource(c.GetString("source"), defaultSource)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	return source, nil
+}
@@ -0,0 +1,61 @@
+package requests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/services"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    services.Source
+		wantErr bool
+	}{
+		{
+			name: "defaults to endpoint source",
+			want: services.SourceOS,
+		},
+		{
+			name:  "s3",
+			query: "?source=s3",
+			want:  services.SourceS3,
+		},
+		{
+			name:  "merged is case insensitive",
+			query: "?source=Merged",
+			want:  services.SourceMerged,
+		},
+		{
+			name:    "unknown source",
+			query:   "?source=dynamo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test"+tt.query, nil))
+
+			ctrl := &web.Controller{}
+			ctrl.Init(ctx, "", "", nil)
+
+			got, err := GetSource(ctrl, services.SourceOS)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -13,29 +13,52 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestSendPropertyDetailsResponses(t *testing.T) {
+func TestSendBulkPropertyResponse(t *testing.T) {
+	found := createMockPropertyResponse("123", true)
+	other := createMockPropertyResponse("456", false)
+
 	tests := []struct {
 		name           string
-		inputData      []structs.PropertyDetailsResponse
+		inputData      structs.BulkPropertyResponse
 		expectedStatus int
 	}{
 		{
 			name: "successful response with multiple properties",
-			inputData: []structs.PropertyDetailsResponse{
-				createMockPropertyResponse("123", true),
-				createMockPropertyResponse("456", false),
+			inputData: structs.BulkPropertyResponse{
+				Summary: structs.BulkSummary{Total: 2, OK: 2},
+				Items: []structs.BulkItem{
+					{ID: "123", Status: structs.BulkItemOK, Data: &found, LatencyMs: 12},
+					{ID: "456", Status: structs.BulkItemOK, Data: &other, LatencyMs: 30},
+				},
 			},
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:           "successful response with empty array",
-			inputData:      []structs.PropertyDetailsResponse{},
+			name: "successful response with empty array",
+			inputData: structs.BulkPropertyResponse{
+				Items: []structs.BulkItem{},
+			},
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name: "successful response with single property",
-			inputData: []structs.PropertyDetailsResponse{
-				createMockPropertyResponse("789", true),
+			name: "successful response with failed properties",
+			inputData: structs.BulkPropertyResponse{
+				Summary: structs.BulkSummary{Total: 3, OK: 1, NotFound: 1, Error: 1},
+				Items: []structs.BulkItem{
+					{ID: "123", Status: structs.BulkItemOK, Data: &found, LatencyMs: 12},
+					{
+						ID:        "789",
+						Status:    structs.BulkItemNotFound,
+						Error:     &structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+						LatencyMs: 5,
+					},
+					{
+						ID:        "790",
+						Status:    structs.BulkItemError,
+						Error:     &structs.ErrorResponse{Code: "upstream_timeout", Message: "Request timed out"},
+						LatencyMs: 10000,
+					},
+				},
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -56,7 +79,7 @@ func TestSendPropertyDetailsResponses(t *testing.T) {
 			ctrl.Init(ctx, "", "", nil)
 
 			// Call the function being tested
-			SendPropertyDetailsResponses(ctrl, tt.inputData)
+			SendBulkPropertyResponse(ctrl, tt.inputData)
 
 			// Get the response
 			resp := w.Result()
@@ -64,26 +87,13 @@ func TestSendPropertyDetailsResponses(t *testing.T) {
 			// Check the status code
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
 
-			// For successful responses, verify the JSON body
-			if tt.expectedStatus == http.StatusOK {
-				// Decode the response body
-				var decodedResponse []structs.PropertyDetailsResponse
-				err := json.NewDecoder(w.Body).Decode(&decodedResponse)
-				assert.NoError(t, err)
-
-				// Compare the decoded response with input data
-				assert.Equal(t, len(tt.inputData), len(decodedResponse))
-
-				if len(tt.inputData) > 0 {
-					// Verify specific fields from the first item
-					assert.Equal(t, tt.inputData[0].ID, decodedResponse[0].ID)
-					assert.Equal(t, tt.inputData[0].Published, decodedResponse[0].Published)
-					assert.Equal(t, tt.inputData[0].Property.PropertyName, decodedResponse[0].Property.PropertyName)
-				}
-
-				// Verify Content-Type header
-				assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
-			}
+			// Compare the response body with the input data
+			expected, err := json.Marshal(tt.inputData)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), w.Body.String())
+
+			// Verify Content-Type header
+			assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
 		})
 	}
 }
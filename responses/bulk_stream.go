@@ -0,0 +1,70 @@
+package responses
+
+import (
+	"beego-api-service/structs"
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Media types of the streaming bulk responses.
+const (
+	MediaTypeNDJSON      = "application/x-ndjson"
+	MediaTypeEventStream = "text/event-stream"
+)
+
+// BulkStream writes the items of a bulk response one at a time, flushing
+// each, as newline-delimited JSON or server-sent events. It is not safe for
+// concurrent use.
+type BulkStream struct {
+	c           *web.Controller
+	eventStream bool
+}
+
+// StartBulkStream sends the headers of a streamed bulk response in
+// mediaType, MediaTypeNDJSON or MediaTypeEventStream.
+func StartBulkStream(c *web.Controller, mediaType string) *BulkStream {
+	c.Ctx.Output.Header("Content-Type", mediaType+"; charset=utf-8")
+	c.Ctx.Output.Header("Cache-Control", "no-cache")
+	// Stop reverse proxies such as nginx from buffering the stream.
+	c.Ctx.Output.Header("X-Accel-Buffering", "no")
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	c.Ctx.ResponseWriter.Flush()
+	return &BulkStream{c: c, eventStream: mediaType == MediaTypeEventStream}
+}
+
+// WriteItem writes one property, as an "item" event for server-sent events.
+func (s *BulkStream) WriteItem(item structs.BulkItem) error {
+	return s.write("item", item)
+}
+
+// WriteSummary writes the final record, as a "summary" event for
+// server-sent events.
+func (s *BulkStream) WriteSummary(summary structs.BulkStreamSummary) error {
+	return s.write("summary", summary)
+}
+
+func (s *BulkStream) write(event string, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if s.eventStream {
+		buf.WriteString("event: " + event + "\ndata: ")
+		buf.Write(data)
+		buf.WriteString("\n\n")
+	} else {
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := s.c.Ctx.ResponseWriter.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	s.c.Ctx.ResponseWriter.Flush()
+	return nil
+}
@@ -0,0 +1,66 @@
+package responses
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkStream(t *testing.T) {
+	item := structs.BulkItem{
+		ID:        "HA-1",
+		Status:    structs.BulkItemNotFound,
+		Error:     &structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+		LatencyMs: 5,
+	}
+	summary := structs.BulkStreamSummary{Summary: structs.BulkSummary{Total: 1, NotFound: 1}}
+
+	tests := []struct {
+		name         string
+		mediaType    string
+		expectedBody string
+	}{
+		{
+			name:      "NDJSON",
+			mediaType: MediaTypeNDJSON,
+			expectedBody: `{"ID":"HA-1","Status":"not_found","Error":{"Code":"property_not_found","Message":"Property not found"},"LatencyMs":5}` + "\n" +
+				`{"Summary":{"Total":1,"OK":0,"NotFound":1,"Error":0}}` + "\n",
+		},
+		{
+			name:      "server-sent events",
+			mediaType: MediaTypeEventStream,
+			expectedBody: "event: item\n" +
+				`data: {"ID":"HA-1","Status":"not_found","Error":{"Code":"property_not_found","Message":"Property not found"},"LatencyMs":5}` + "\n\n" +
+				"event: summary\n" +
+				`data: {"Summary":{"Total":1,"OK":0,"NotFound":1,"Error":0}}` + "\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test", nil))
+
+			controller := web.Controller{}
+			controller.Init(ctx, "", "", nil)
+
+			stream := StartBulkStream(&controller, tt.mediaType)
+			assert.True(t, w.Flushed)
+
+			assert.NoError(t, stream.WriteItem(item))
+			assert.NoError(t, stream.WriteSummary(summary))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.mediaType+"; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package responses
+
+import (
+	"beego-api-service/structs"
+	"net/http"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+func SendDiscrepancyReportResponse(c *web.Controller, data structs.DiscrepancyReport) {
+	sendNegotiated(c, data, http.StatusOK)
+}
+
+func SendReconciliationReportResponse(c *web.Controller, data structs.ReconciliationReport) {
+	sendNegotiated(c, data, http.StatusOK)
+}
@@ -0,0 +1,54 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendReconciliationReportResponse(t *testing.T) {
+	input := structs.ReconciliationReport{
+		Properties: []structs.DiscrepancyReport{
+			{
+				ID: "HA-1",
+				Discrepancies: []structs.FieldDiscrepancy{
+					{Field: "Property.PropertyName", S3: "Sea View", OS: "Sea-View"},
+				},
+			},
+			{
+				ID:            "HA-2",
+				Discrepancies: []structs.FieldDiscrepancy{},
+				Error:         &structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+			},
+		},
+		Summary: structs.ReconciliationSummary{
+			Total:             2,
+			WithDiscrepancies: 1,
+			Failed:            1,
+			Fields:            map[string]int{"Property.PropertyName": 1},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("GET", "/test", nil))
+
+	controller := web.Controller{}
+	controller.Init(ctx, "", "", nil)
+
+	SendReconciliationReportResponse(&controller, input)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response structs.ReconciliationReport
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, input, response)
+}
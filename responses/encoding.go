@@ -0,0 +1,488 @@
+package responses
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// xmlRootElement wraps every XML payload.
+const xmlRootElement = "Response"
+
+// field is one member of an object, kept in the order encoding/json writes it.
+type field struct {
+	Name  string
+	Value interface{}
+}
+
+// object is a JSON object in member order.
+type object []field
+
+// payloadTree converts a payload to the shape encoding/json gives it, so that
+// every format has the same field names and omits the same fields. Values are
+// nil, bool, int64, uint64, float64, string, []interface{} or object.
+func payloadTree(payload interface{}) (interface{}, error) {
+	return valueTree(reflect.ValueOf(payload))
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func valueTree(value reflect.Value) (interface{}, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+	if !value.CanInterface() {
+		return nil, fmt.Errorf("unexported value of type %s", value.Type())
+	}
+	if number, ok := value.Interface().(json.Number); ok {
+		return numberTree(number)
+	}
+	if value.Type().Implements(jsonMarshalerType) && (value.Kind() != reflect.Ptr || !value.IsNil()) {
+		return marshalerTree(value.Interface().(json.Marshaler))
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return valueTree(value.Elem())
+	case reflect.Struct:
+		members := object{}
+		if err := appendStructFields(&members, value); err != nil {
+			return nil, err
+		}
+		return members, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		}
+		keys := value.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = fmt.Sprint(key.Interface())
+		}
+		sort.Sort(byName{names, keys})
+		members := make(object, len(keys))
+		for i, key := range keys {
+			child, err := valueTree(value.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			members[i] = field{Name: names[i], Value: child}
+		}
+		return members, nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+		elements := make([]interface{}, value.Len())
+		for i := range elements {
+			child, err := valueTree(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = child
+		}
+		return elements, nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", value.Type())
+}
+
+// appendStructFields adds the fields of a struct, inlining embedded structs
+// without a JSON name the way encoding/json does.
+func appendStructFields(members *object, value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if structField.PkgPath != "" && !structField.Anonymous {
+			continue
+		}
+		tag := structField.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if structField.Anonymous && name == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := appendStructFields(members, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if structField.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = structField.Name
+		}
+		if strings.Contains(","+options+",", ",omitempty,") && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		child, err := valueTree(fieldValue)
+		if err != nil {
+			return err
+		}
+		*members = append(*members, field{Name: name, Value: child})
+	}
+	return nil
+}
+
+// isEmptyValue matches the omitempty rule of encoding/json.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+// marshalerTree converts a value with its own JSON encoding, such as
+// time.Time, through that encoding.
+func marshalerTree(marshaler json.Marshaler) (interface{}, error) {
+	encoded, err := marshaler.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return valueTree(reflect.ValueOf(generic))
+}
+
+// numberTree keeps whole numbers, such as those of projected fields, as
+// integers.
+func numberTree(number json.Number) (interface{}, error) {
+	if integer, err := number.Int64(); err == nil {
+		return integer, nil
+	}
+	return number.Float64()
+}
+
+// byName sorts map keys by their string form.
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (b byName) Len() int           { return len(b.names) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// formatScalar writes a number or boolean as JSON would.
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// encodeXML writes the payload as elements named after its fields inside a
+// Response element. List elements are Item elements, and map keys that are
+// not valid element names become Entry elements with a Key attribute.
+func encodeXML(payload interface{}) ([]byte, error) {
+	tree, err := payloadTree(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeXMLElement(&buf, xmlRootElement, tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeXMLElement(buf *bytes.Buffer, name string, value interface{}) error {
+	start, end := name, name
+	if !isXMLName(name) {
+		var key bytes.Buffer
+		if err := xml.EscapeText(&key, []byte(name)); err != nil {
+			return err
+		}
+		start, end = `Entry Key="`+key.String()+`"`, "Entry"
+	}
+
+	if value == nil {
+		buf.WriteString("<" + start + "/>")
+		return nil
+	}
+	buf.WriteString("<" + start + ">")
+	switch v := value.(type) {
+	case object:
+		for _, member := range v {
+			if err := writeXMLElement(buf, member.Name, member.Value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, element := range v {
+			if err := writeXMLElement(buf, "Item", element); err != nil {
+				return err
+			}
+		}
+	case string:
+		if err := xml.EscapeText(buf, []byte(v)); err != nil {
+			return err
+		}
+	default:
+		buf.WriteString(formatScalar(v))
+	}
+	buf.WriteString("</" + end + ">")
+	return nil
+}
+
+// isXMLName reports whether name can be used as an element name as is.
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// yamlPlain matches strings that can be written without quotes.
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ ./-]*[A-Za-z0-9_./-]$|^[A-Za-z_]$`)
+
+// yamlReserved are plain scalars a YAML parser would not read as strings.
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// encodeYAML writes the payload as block style YAML.
+func encodeYAML(payload interface{}) ([]byte, error) {
+	tree, err := payloadTree(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, tree, 0, false)
+	return buf.Bytes(), nil
+}
+
+// writeYAML writes value at indent. inline means the first line continues
+// one that is already started, such as a list item's "- ".
+func writeYAML(buf *bytes.Buffer, value interface{}, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+	switch v := value.(type) {
+	case object:
+		if len(v) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+		for i, member := range v {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(yamlString(member.Name) + ":")
+			writeYAMLValue(buf, member.Value, indent+2)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+		for i, element := range v {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString("- ")
+			writeYAML(buf, element, indent+2, true)
+		}
+	case string:
+		buf.WriteString(yamlString(v) + "\n")
+	case nil:
+		buf.WriteString("null\n")
+	default:
+		buf.WriteString(formatScalar(v) + "\n")
+	}
+}
+
+// writeYAMLValue writes the value of a mapping key, on the same line unless
+// it is a non-empty object or list.
+func writeYAMLValue(buf *bytes.Buffer, value interface{}, indent int) {
+	switch v := value.(type) {
+	case object:
+		if len(v) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, v, indent, false)
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, v, indent, false)
+			return
+		}
+	}
+	buf.WriteString(" ")
+	writeYAML(buf, value, indent, true)
+}
+
+// yamlString quotes text unless it reads back as the same string. Go's
+// escapes are valid in YAML double-quoted scalars.
+func yamlString(text string) string {
+	if yamlPlain.MatchString(text) && !yamlReserved[strings.ToLower(text)] {
+		return text
+	}
+	return strconv.Quote(text)
+}
+
+// encodeMessagePack writes the payload in the MessagePack format, using the
+// smallest encoding of each value.
+func encodeMessagePack(payload interface{}) ([]byte, error) {
+	tree, err := payloadTree(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeMessagePack(&buf, tree)
+	return buf.Bytes(), nil
+}
+
+func writeMessagePack(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int64:
+		writeMessagePackInt(buf, v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			writeMessagePackInt(buf, int64(v))
+			return
+		}
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, v)
+	case float64:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeMessagePackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMessagePackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, element := range v {
+			writeMessagePack(buf, element)
+		}
+	case object:
+		writeMessagePackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, member := range v {
+			writeMessagePack(buf, member.Name)
+			writeMessagePack(buf, member.Value)
+		}
+	}
+}
+
+func writeMessagePackInt(buf *bytes.Buffer, v int64) {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		buf.WriteByte(byte(v))
+	case v < 0 && v >= -32:
+		buf.WriteByte(byte(int8(v)))
+	case v >= 0 && v <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(v))
+	case v >= 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(v))
+	case v >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(v)))
+	case v >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(v))
+	case v >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(v))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, v)
+	}
+}
+
+// writeMessagePackHeader writes the type and length of a string, array or
+// map: fixed up to fixLimit, then 8-bit (if the type has one), 16-bit or
+// 32-bit lengths.
+func writeMessagePackHeader(buf *bytes.Buffer, length int, fixed byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case length < fixLimit:
+		buf.WriteByte(fixed | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
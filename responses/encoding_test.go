@@ -0,0 +1,200 @@
+package responses
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type encodingSample struct {
+	Name     string            `json:"Name"`
+	Tags     []string          `json:"Tags"`
+	Scores   map[string]int    `json:"Scores"`
+	Rooms    []encodingRoom    `json:"Rooms"`
+	Note     *string           `json:"Note"`
+	Hidden   string            `json:"-"`
+	Optional string            `json:"Optional,omitempty"`
+	Extra    map[string]string `json:"Extra,omitempty"`
+}
+
+type encodingRoom struct {
+	Kind string  `json:"Kind"`
+	Size float64 `json:"Size"`
+}
+
+func newEncodingSample() encodingSample {
+	return encodingSample{
+		Name:   "Villa <Rosa>",
+		Tags:   []string{"pool", "yes"},
+		Scores: map[string]int{"2024 rating": 5, "clean": 4},
+		Rooms:  []encodingRoom{{Kind: "bedroom", Size: 12.5}, {Kind: "bath", Size: 6}},
+		Hidden: "secret",
+	}
+}
+
+func TestPayloadTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    interface{}
+	}{
+		{
+			name:    "struct fields in order, skipping ignored and empty omitempty fields",
+			payload: encodingRoom{Kind: "bath", Size: 6},
+			want:    object{{"Kind", "bath"}, {"Size", 6.0}},
+		},
+		{
+			name: "embedded struct is inlined",
+			payload: struct {
+				encodingRoom
+				Floor int `json:"Floor"`
+			}{encodingRoom{Kind: "bath", Size: 6}, 2},
+			want: object{{"Kind", "bath"}, {"Size", 6.0}, {"Floor", int64(2)}},
+		},
+		{
+			name:    "projected fields keep whole numbers as integers",
+			payload: map[string]interface{}{"Feed": json.Number("12"), "Lat": json.Number("40.5")},
+			want:    object{{"Feed", int64(12)}, {"Lat", 40.5}},
+		},
+		{
+			name:    "nil",
+			payload: nil,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := payloadTree(tt.payload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	sample, err := payloadTree(newEncodingSample())
+	assert.NoError(t, err)
+	var names []string
+	for _, member := range sample.(object) {
+		names = append(names, member.Name)
+	}
+	assert.Equal(t, []string{"Name", "Tags", "Scores", "Rooms", "Note"}, names)
+}
+
+func TestEncodeXML(t *testing.T) {
+	body, err := encodeXML(newEncodingSample())
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<Response><Name>Villa &lt;Rosa&gt;</Name>`+
+		`<Tags><Item>pool</Item><Item>yes</Item></Tags>`+
+		`<Scores><Entry Key="2024 rating">5</Entry><clean>4</clean></Scores>`+
+		`<Rooms><Item><Kind>bedroom</Kind><Size>12.5</Size></Item><Item><Kind>bath</Kind><Size>6</Size></Item></Rooms>`+
+		`<Note/></Response>`, string(body))
+}
+
+func TestIsXMLName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Property", true},
+		{"_id", true},
+		{"GeoInfo.City", true},
+		{"Bed-Room2", true},
+		{"2024", false},
+		{"two words", false},
+		{"xmlns", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isXMLName(tt.name))
+		})
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	body, err := encodeYAML(newEncodingSample())
+	assert.NoError(t, err)
+	assert.Equal(t, `Name: "Villa <Rosa>"
+Tags:
+  - pool
+  - "yes"
+Scores:
+  "2024 rating": 5
+  clean: 4
+Rooms:
+  - Kind: bedroom
+    Size: 12.5
+  - Kind: bath
+    Size: 6
+Note: null
+`, string(body))
+
+	body, err = encodeYAML(map[string]interface{}{"Empty": map[string]string{}, "List": []int{}, "Nested": [][]int{{1, 2}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Empty: {}\nList: []\nNested:\n  - - 1\n    - 2\n", string(body))
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Miami Beach", "Miami Beach"},
+		{"https://example.com/a.jpg", `"https://example.com/a.jpg"`},
+		{"GeoInfo.City", "GeoInfo.City"},
+		{"true", `"true"`},
+		{"No", `"No"`},
+		{"12", `"12"`},
+		{"-80.1", `"-80.1"`},
+		{"trailing ", `"trailing "`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"", `""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, yamlString(tt.text))
+		})
+	}
+}
+
+func TestEncodeMessagePack(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    []byte
+	}{
+		{
+			name:    "object",
+			payload: encodingRoom{Kind: "bath", Size: 6},
+			want: []byte{0x82,
+				0xa4, 'K', 'i', 'n', 'd', 0xa4, 'b', 'a', 't', 'h',
+				0xa4, 'S', 'i', 'z', 'e', 0xcb, 0x40, 0x18, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "scalars",
+			payload: []interface{}{nil, true, false, 7, -3, 200, -200, 70000, int64(1) << 40},
+			want: []byte{0x99, 0xc0, 0xc3, 0xc2, 0x07, 0xfd,
+				0xcc, 0xc8,
+				0xd1, 0xff, 0x38,
+				0xce, 0x00, 0x01, 0x11, 0x70,
+				0xcf, 0, 0, 0x01, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "long string",
+			payload: string(make([]byte, 40)),
+			want:    append([]byte{0xd9, 40}, make([]byte, 40)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeMessagePack(tt.payload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package responses
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// MediaTypeProblemJSON is the media type of RFC 7807 problem details.
+const MediaTypeProblemJSON = "application/problem+json"
+
+// SendErrorResponse answers with an error identified by a stable code, such
+// as missing_parameter, and a message for people.
+func SendErrorResponse(c *web.Controller, code, message string, status int) {
+	SendErrorCodeResponse(c, structs.ErrorResponse{Code: code, Message: message}, status)
+}
+
+// SendErrorCodeResponse answers with data as problem details. They are sent
+// in the media type the client accepts; clients accepting none of them get
+// application/problem+json rather than a 406, so the error itself is not
+// lost.
+func SendErrorCodeResponse(c *web.Controller, data structs.ErrorResponse, status int) {
+	sendProblem(c, newProblem(c, data, status))
+}
+
+// newProblem describes data as RFC 7807 problem details. The type is the
+// problemTypeBaseURL setting followed by the error code, or about:blank when
+// it is not set; the instance is the request path.
+func newProblem(c *web.Controller, data structs.ErrorResponse, status int) structs.Problem {
+	problem := structs.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    data.Message,
+		Instance:  c.Ctx.Input.URL(),
+		Code:      data.Code,
+		RequestID: requestID(c),
+		Fields:    data.Fields,
+	}
+	if base := web.AppConfig.DefaultString("problemTypeBaseURL", ""); base != "" {
+		problem.Type = strings.TrimSuffix(base, "/") + "/" + data.Code
+	}
+	if problem.Title == "" {
+		// Non-standard statuses, such as 499 for canceled requests
+		problem.Title = data.Message
+	}
+	return problem
+}
+
+// sendProblem writes problem in the negotiated media type, JSON being sent as
+// application/problem+json. A failed write is only logged.
+func sendProblem(c *web.Controller, problem structs.Problem) {
+	mediaType, ok := NegotiateMediaType(c.Ctx.Input.Header("Accept"))
+	if ok && mediaType != MediaTypeJSON {
+		sendEncoded(c, problem, problem.Status, mediaType)
+		return
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		log.Printf("Failed to encode error response: %v", err)
+		problem.Status = http.StatusInternalServerError
+		body = []byte(`{"type":"about:blank","title":"Internal Server Error","status":500,"code":"` + errorInternal + `"}`)
+	}
+	addVary(c, "Accept")
+	c.Ctx.Output.Header("Content-Type", MediaTypeProblemJSON)
+	c.Ctx.Output.SetStatus(problem.Status)
+	if err := c.Ctx.Output.Body(body); err != nil {
+		log.Printf("Failed to write error response: %v", err)
+	}
+}
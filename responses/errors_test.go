@@ -0,0 +1,122 @@
+package responses
+
+import (
+	"beego-api-service/structs"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendErrorCodeResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       structs.ErrorResponse
+		status      int
+		requestID   string
+		typeBaseURL string
+		want        structs.Problem
+	}{
+		{
+			name:      "Not found",
+			input:     structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+			status:    http.StatusNotFound,
+			requestID: "req-123",
+			want: structs.Problem{
+				Type:      "about:blank",
+				Title:     "Not Found",
+				Status:    http.StatusNotFound,
+				Detail:    "Property not found",
+				Instance:  "/test",
+				Code:      "property_not_found",
+				RequestID: "req-123",
+			},
+		},
+		{
+			name: "Bad gateway with invalid fields",
+			input: structs.ErrorResponse{
+				Code:    "bad_upstream_payload",
+				Message: "Upstream returned an invalid property document",
+				Fields:  []structs.FieldError{{Path: "S3.ID", Reason: "is required"}},
+			},
+			status:      http.StatusBadGateway,
+			requestID:   "req-456",
+			typeBaseURL: "https://api.example.com/problems/",
+			want: structs.Problem{
+				Type:      "https://api.example.com/problems/bad_upstream_payload",
+				Title:     "Bad Gateway",
+				Status:    http.StatusBadGateway,
+				Detail:    "Upstream returned an invalid property document",
+				Instance:  "/test",
+				Code:      "bad_upstream_payload",
+				RequestID: "req-456",
+				Fields:    []structs.FieldError{{Path: "S3.ID", Reason: "is required"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			web.AppConfig.Set("problemTypeBaseURL", tt.typeBaseURL)
+			defer web.AppConfig.Set("problemTypeBaseURL", "")
+
+			w := httptest.NewRecorder()
+			context := context.NewContext()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(RequestIDHeader, tt.requestID)
+			context.Reset(w, req)
+			AssignRequestID(context)
+
+			controller := web.Controller{}
+			controller.Init(context, "", "", nil)
+
+			SendErrorCodeResponse(&controller, tt.input, tt.status)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, MediaTypeProblemJSON, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.requestID, w.Header().Get(RequestIDHeader))
+
+			var response structs.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.want, response)
+		})
+	}
+}
+
+func TestAssignRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "ID from the client", header: "4f1c-9a2e", wantKept: true},
+		{name: "no ID"},
+		{name: "unsafe ID replaced", header: "bad id\r\nX-Evil: 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+			ctx.Reset(w, req)
+
+			AssignRequestID(ctx)
+
+			controller := web.Controller{}
+			controller.Init(ctx, "", "", nil)
+			id := requestID(&controller)
+			assert.Equal(t, id, w.Header().Get(RequestIDHeader))
+			if tt.wantKept {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+		})
+	}
+}
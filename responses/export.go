@@ -0,0 +1,217 @@
+package responses
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Media types of the spreadsheet exports.
+const (
+	MediaTypeCSV  = "text/csv"
+	MediaTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// TableWriter writes the rows of an export as they are produced. Cells are
+// strings, numbers or booleans. Close must be called to finish the file.
+type TableWriter interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// StartTableExport sends the headers of a CSV or XLSX download named
+// filename, without extension, followed by its header row.
+func StartTableExport(c *web.Controller, mediaType, filename string, header []string) (TableWriter, error) {
+	extension := "csv"
+	if mediaType == MediaTypeXLSX {
+		extension = "xlsx"
+	}
+	c.Ctx.Output.Header("Content-Type", mediaType)
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+extension))
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusOK)
+
+	var table TableWriter
+	if mediaType == MediaTypeXLSX {
+		xlsx, err := newXLSXTable(c.Ctx.ResponseWriter, c.Ctx.ResponseWriter.Flush)
+		if err != nil {
+			return nil, err
+		}
+		table = xlsx
+	} else {
+		table = &csvTable{writer: csv.NewWriter(c.Ctx.ResponseWriter), flush: c.Ctx.ResponseWriter.Flush}
+	}
+
+	cells := make([]interface{}, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	return table, table.WriteRow(cells)
+}
+
+type csvTable struct {
+	writer *csv.Writer
+	flush  func()
+}
+
+func (t *csvTable) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = csvCell(cell)
+	}
+	if err := t.writer.Write(record); err != nil {
+		return err
+	}
+	t.writer.Flush()
+	t.flush()
+	return t.writer.Error()
+}
+
+func (t *csvTable) Close() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+// csvCell formats a cell. Text that a spreadsheet would run as a formula is
+// prefixed with a quote; numbers, even negative ones stored as text such as
+// coordinates, are left alone.
+func csvCell(cell interface{}) string {
+	text := fmt.Sprint(cell)
+	if text == "" || !strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return text
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return text
+	}
+	return "'" + text
+}
+
+// xlsxFlushRows is how many rows of a workbook are compressed before they
+// are flushed to the client. The compressor otherwise holds back tens of
+// kilobytes, and the zip writer a few more, until the workbook is closed.
+const xlsxFlushRows = 100
+
+// xlsxTable streams a workbook with one sheet. The sheet is the last entry of
+// the zip file, so rows are written as they are known and sent to the client
+// every xlsxFlushRows rows, keeping memory bounded however long the export.
+type xlsxTable struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	// deflate compresses the sheet; flushing it emits every row so far.
+	deflate *flate.Writer
+	flush   func()
+	rows    int
+}
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Properties" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func newXLSXTable(w io.Writer, flush func()) (*xlsxTable, error) {
+	t := &xlsxTable{archive: zip.NewWriter(w), flush: flush}
+	t.archive.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		var err error
+		t.deflate, err = flate.NewWriter(out, flate.DefaultCompression)
+		return t.deflate, err
+	})
+	for _, part := range xlsxParts {
+		entry, err := t.archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := t.archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	t.sheet = bufio.NewWriter(entry)
+	t.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return t, nil
+}
+
+func (t *xlsxTable) WriteRow(cells []interface{}) error {
+	t.rows++
+	fmt.Fprintf(t.sheet, `<row r="%d">`, t.rows)
+	for i, cell := range cells {
+		ref := xlsxColumn(i) + strconv.Itoa(t.rows)
+		switch value := cell.(type) {
+		case int, int64, float64:
+			fmt.Fprintf(t.sheet, `<c r="%s"><v>%v</v></c>`, ref, value)
+		case bool:
+			flag := 0
+			if value {
+				flag = 1
+			}
+			fmt.Fprintf(t.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, flag)
+		default:
+			fmt.Fprintf(t.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(t.sheet, []byte(fmt.Sprint(value))); err != nil {
+				return err
+			}
+			t.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	t.sheet.WriteString(`</row>`)
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	if t.rows%xlsxFlushRows != 0 {
+		return nil
+	}
+	if err := t.deflate.Flush(); err != nil {
+		return err
+	}
+	if err := t.archive.Flush(); err != nil {
+		return err
+	}
+	t.flush()
+	return nil
+}
+
+func (t *xlsxTable) Close() error {
+	t.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.archive.Close()
+}
+
+// xlsxColumn names the column with zero-based index i: A, B, ..., Z, AA, ...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
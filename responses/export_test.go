@@ -0,0 +1,102 @@
+package responses
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestExport(t *testing.T, mediaType string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("GET", "/test", nil))
+
+	controller := web.Controller{}
+	controller.Init(ctx, "", "", nil)
+
+	table, err := StartTableExport(&controller, mediaType, "properties", []string{"ID", "City", "Bedrooms", "Published"})
+	assert.NoError(t, err)
+	assert.NoError(t, table.WriteRow([]interface{}{"HA-1", "Ville, \"Nord\"", 2, true}))
+	assert.NoError(t, table.WriteRow([]interface{}{"HA-2", "=HYPERLINK(\"x\")", "-12.5", false}))
+	assert.NoError(t, table.Close())
+	return w
+}
+
+func TestStartTableExportCSV(t *testing.T) {
+	w := startTestExport(t, MediaTypeCSV)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="properties.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "ID,City,Bedrooms,Published\n"+
+		"HA-1,\"Ville, \"\"Nord\"\"\",2,true\n"+
+		"HA-2,\"'=HYPERLINK(\"\"x\"\")\",-12.5,false\n", w.Body.String())
+}
+
+func TestStartTableExportXLSX(t *testing.T) {
+	w := startTestExport(t, MediaTypeXLSX)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MediaTypeXLSX, w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="properties.xlsx"`, w.Header().Get("Content-Disposition"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		parts[file.Name] = string(content)
+	}
+
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts, "xl/workbook.xml")
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">ID</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">Ville, &#34;Nord&#34;</t></is></c><c r="C2"><v>2</v></c><c r="D2" t="b"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">=HYPERLINK(&#34;x&#34;)</t></is></c>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
+
+func TestXLSXTableFlushesRows(t *testing.T) {
+	var body bytes.Buffer
+	var flushed []int
+	table, err := newXLSXTable(&body, func() { flushed = append(flushed, body.Len()) })
+	assert.NoError(t, err)
+
+	for i := 0; i < 2*xlsxFlushRows+50; i++ {
+		assert.NoError(t, table.WriteRow([]interface{}{"HA-" + strconv.Itoa(i), i}))
+	}
+	// Every xlsxFlushRows rows reach the client before the workbook is closed.
+	if assert.Len(t, flushed, 2) {
+		assert.Greater(t, flushed[1], flushed[0])
+	}
+	assert.NoError(t, table.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+	assert.NoError(t, err)
+	sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(sheet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `<row r="250"><c r="A250" t="inlineStr"><is><t xml:space="preserve">HA-249</t></is></c><c r="B250"><v>249</v></c></row></sheetData>`)
+}
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "ZZ", xlsxColumn(701))
+	assert.Equal(t, "AAA", xlsxColumn(702))
+}
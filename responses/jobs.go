@@ -0,0 +1,16 @@
+package responses
+
+import (
+	"beego-api-service/structs"
+	"net/http"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+func SendJobProgressResponse(c *web.Controller, data structs.JobProgress, status int) {
+	sendNegotiated(c, data, status)
+}
+
+func SendJobResultsResponse(c *web.Controller, data structs.JobResultsPage) {
+	sendNegotiated(c, data, http.StatusOK)
+}
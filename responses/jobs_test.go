@@ -0,0 +1,77 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendJobProgressResponse(t *testing.T) {
+	input := structs.JobProgress{
+		ID:        "0123456789abcdef",
+		Status:    "running",
+		Total:     3,
+		Done:      1,
+		Failed:    1,
+		Pending:   1,
+		CreatedAt: "2025-01-09T06:11:56Z",
+		UpdatedAt: "2025-01-09T06:12:30Z",
+	}
+
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("POST", "/test", nil))
+
+	controller := web.Controller{}
+	controller.Init(ctx, "", "", nil)
+
+	SendJobProgressResponse(&controller, input, http.StatusAccepted)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response structs.JobProgress
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, input, response)
+}
+
+func TestSendJobResultsResponse(t *testing.T) {
+	next := 1
+	input := structs.JobResultsPage{
+		JobID:      "0123456789abcdef",
+		Offset:     0,
+		Limit:      1,
+		NextOffset: &next,
+		Items: []structs.BulkItem{
+			{
+				ID:        "HA-1",
+				Status:    structs.BulkItemNotFound,
+				Error:     &structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+				LatencyMs: 12,
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("GET", "/test", nil))
+
+	controller := web.Controller{}
+	controller.Init(ctx, "", "", nil)
+
+	SendJobResultsResponse(&controller, input)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response structs.JobResultsPage
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, input, response)
+}
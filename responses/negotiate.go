@@ -0,0 +1,221 @@
+package responses
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Media types of the negotiated responses.
+const (
+	MediaTypeJSON        = "application/json"
+	MediaTypeXML         = "application/xml"
+	MediaTypeYAML        = "application/yaml"
+	MediaTypeMessagePack = "application/msgpack"
+)
+
+// Error codes of the responses package itself.
+const (
+	errorNotAcceptable = "not_acceptable"
+	errorInternal      = "internal_error"
+)
+
+// format is a response encoding. The first media type is sent as the
+// Content-Type; the others are aliases clients may ask for. JSON has no
+// encode func and is served by beego.
+type format struct {
+	mediaTypes []string
+	encode     func(payload interface{}) ([]byte, error)
+}
+
+// formats are in order of preference, for clients that accept several
+// equally.
+var formats = []format{
+	{mediaTypes: []string{MediaTypeJSON}},
+	{mediaTypes: []string{MediaTypeXML, "text/xml"}, encode: encodeXML},
+	{mediaTypes: []string{MediaTypeYAML, "application/x-yaml", "text/yaml", "text/x-yaml"}, encode: encodeYAML},
+	{mediaTypes: []string{MediaTypeMessagePack, "application/x-msgpack", "application/vnd.msgpack"}, encode: encodeMessagePack},
+}
+
+// mediaRange is one entry of an Accept header, such as text/* or
+// application/xml;q=0.5.
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	quality   float64
+}
+
+// NegotiateMediaType picks the media type of a response from an Accept
+// header: the one with the highest quality among JSON, XML, YAML and
+// MessagePack, preferring them in that order. No header selects JSON, and so
+// does */* when the client's first choice is none of them, as with browsers
+// asking for HTML, then XML, then anything. JSON-based types such as
+// application/problem+json select JSON. ok is false when the header accepts
+// none of them.
+func NegotiateMediaType(accept string) (mediaType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return MediaTypeJSON, true
+	}
+
+	ranges := parseAccept(accept)
+	best := 0.0
+	for _, f := range formats {
+		for _, alias := range f.mediaTypes {
+			if quality, _ := acceptQuality(ranges, alias); quality > best {
+				best, mediaType = quality, f.mediaTypes[0]
+			}
+		}
+	}
+
+	if mediaType != "" && mediaType != MediaTypeJSON && best < topQuality(ranges) {
+		if quality, specificity := acceptQuality(ranges, MediaTypeJSON); quality > 0 && specificity == 0 {
+			return MediaTypeJSON, true
+		}
+	}
+	return mediaType, mediaType != ""
+}
+
+// parseAccept reads the media ranges of an Accept header, keeping those with
+// q=0 since they exclude a type that a wildcard would accept. Subtypes with
+// the +json suffix, like application/problem+json, are read as
+// application/json.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "/")
+		if !ok || mediaType == "" || subtype == "" {
+			continue
+		}
+		if mediaType == "application" && strings.HasSuffix(subtype, "+json") {
+			subtype = "json"
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if name, value, _ := strings.Cut(strings.TrimSpace(param), "="); strings.TrimSpace(name) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, subtype: subtype, quality: quality})
+	}
+	return ranges
+}
+
+// acceptQuality is the quality the most specific matching range gives to
+// mediaType, or 0 if none matches. specificity is 2 for an exact match, 1 for
+// type/* and 0 for */*.
+func acceptQuality(ranges []mediaRange, mediaType string) (quality float64, specificity int) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	specificity = -1
+	for _, r := range ranges {
+		var matched int
+		switch {
+		case r.mediaType == typ && r.subtype == subtype:
+			matched = 2
+		case r.mediaType == typ && r.subtype == "*":
+			matched = 1
+		case r.mediaType == "*" && r.subtype == "*":
+			matched = 0
+		default:
+			continue
+		}
+		if matched > specificity {
+			specificity, quality = matched, r.quality
+		}
+	}
+	return quality, specificity
+}
+
+// topQuality is the highest quality in ranges.
+func topQuality(ranges []mediaRange) float64 {
+	top := 0.0
+	for _, r := range ranges {
+		top = math.Max(top, r.quality)
+	}
+	return top
+}
+
+// sendNegotiated sends data with status in the media type the Accept header
+// asks for, or 406 Not Acceptable if it asks for none the service supports.
+func sendNegotiated(c *web.Controller, data interface{}, status int) {
+	mediaType, ok := NegotiateMediaType(c.Ctx.Input.Header("Accept"))
+	if !ok {
+		SendNotAcceptableResponse(c)
+		return
+	}
+	sendEncoded(c, data, status, mediaType)
+}
+
+// SendNotAcceptableResponse answers 406 Not Acceptable, listing the media
+// types the service can send. The body is problem+json since the client
+// accepts none of them.
+func SendNotAcceptableResponse(c *web.Controller) {
+	supported := make([]string, len(formats))
+	for i, f := range formats {
+		supported[i] = f.mediaTypes[0]
+	}
+	log.Printf("No acceptable media type in Accept: %s", c.Ctx.Input.Header("Accept"))
+	SendErrorResponse(c, errorNotAcceptable,
+		fmt.Sprintf("Not acceptable, supported media types are %s", strings.Join(supported, ", ")),
+		http.StatusNotAcceptable)
+}
+
+// sendEncoded sends data with status in mediaType, one of the negotiated
+// media types. Payloads that cannot be encoded are answered with a 500
+// problem; a failed write is only logged.
+func sendEncoded(c *web.Controller, data interface{}, status int, mediaType string) {
+	addVary(c, "Accept")
+
+	if mediaType == MediaTypeJSON {
+		c.Data["json"] = data
+		c.Ctx.Output.SetStatus(status)
+		if err := c.ServeJSON(); err != nil {
+			log.Printf("Failed to serve JSON response: %v", err)
+		}
+		return
+	}
+
+	var body []byte
+	var err error
+	for _, f := range formats {
+		if f.mediaTypes[0] == mediaType {
+			body, err = f.encode(data)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to encode %s response: %v", mediaType, err)
+		SendErrorResponse(c, errorInternal, "Failed to encode the response", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mediaType
+	if mediaType != MediaTypeMessagePack {
+		contentType += "; charset=utf-8"
+	}
+	c.Ctx.Output.Header("Content-Type", contentType)
+	c.Ctx.Output.SetStatus(status)
+	if err := c.Ctx.Output.Body(body); err != nil {
+		log.Printf("Failed to write %s response: %v", mediaType, err)
+	}
+}
+
+// addVary adds header to the Vary header, keeping the ones already listed.
+func addVary(c *web.Controller, header string) {
+	vary := c.Ctx.ResponseWriter.Header().Get("Vary")
+	for _, listed := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(listed), header) {
+			return
+		}
+	}
+	if vary != "" {
+		header = vary + ", " + header
+	}
+	c.Ctx.Output.Header("Vary", header)
+}
@@ -0,0 +1,154 @@
+package responses
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOK bool
+	}{
+		{name: "no Accept header", want: MediaTypeJSON, wantOK: true},
+		{name: "wildcard", accept: "*/*", want: MediaTypeJSON, wantOK: true},
+		{name: "problem JSON", accept: "application/problem+json", want: MediaTypeJSON, wantOK: true},
+		{name: "vendor JSON beats wildcard", accept: "application/vnd.api+json, application/xml;q=0.9", want: MediaTypeJSON, wantOK: true},
+		{name: "XML", accept: "application/xml", want: MediaTypeXML, wantOK: true},
+		{name: "XML alias", accept: "text/xml", want: MediaTypeXML, wantOK: true},
+		{name: "YAML alias", accept: "application/x-yaml", want: MediaTypeYAML, wantOK: true},
+		{name: "MessagePack", accept: "application/msgpack", want: MediaTypeMessagePack, wantOK: true},
+		{name: "MessagePack alias", accept: "application/vnd.msgpack", want: MediaTypeMessagePack, wantOK: true},
+		{name: "case and parameters ignored", accept: "Application/XML; charset=utf-8", want: MediaTypeXML, wantOK: true},
+		{name: "highest quality wins", accept: "application/json;q=0.4, application/yaml;q=0.8", want: MediaTypeYAML, wantOK: true},
+		{name: "ties keep service order", accept: "application/msgpack, application/xml", want: MediaTypeXML, wantOK: true},
+		{name: "subtype wildcard", accept: "text/*", want: MediaTypeXML, wantOK: true},
+		{name: "specific range beats wildcard", accept: "*/*, application/json;q=0", want: MediaTypeXML, wantOK: true},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: MediaTypeJSON, wantOK: true},
+		{name: "first choice kept over wildcard", accept: "application/xml, */*;q=0.1", want: MediaTypeXML, wantOK: true},
+		{name: "JSON ranked below the others", accept: "text/html, application/yaml;q=0.8, application/json;q=0.5", want: MediaTypeYAML, wantOK: true},
+		{name: "unsupported type", accept: "text/html"},
+		{name: "everything refused", accept: "*/*;q=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NegotiateMediaType(tt.accept)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSendNegotiated(t *testing.T) {
+	data := structs.JobProgress{ID: "job-1", Status: "done", Total: 1, Done: 1}
+
+	tests := []struct {
+		name        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "JSON",
+			accept:      "application/json",
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"ID":"job-1","Status":"done","Total":1,"Done":1,"Failed":0,"Pending":0,"CreatedAt":"","UpdatedAt":""}`,
+		},
+		{
+			name:        "XML",
+			accept:      "application/xml",
+			status:      http.StatusOK,
+			contentType: "application/xml; charset=utf-8",
+			body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<Response><ID>job-1</ID><Status>done</Status><Total>1</Total><Done>1</Done><Failed>0</Failed><Pending>0</Pending><CreatedAt></CreatedAt><UpdatedAt></UpdatedAt></Response>`,
+		},
+		{
+			name:        "YAML",
+			accept:      "application/yaml",
+			status:      http.StatusOK,
+			contentType: "application/yaml; charset=utf-8",
+			body:        "ID: job-1\nStatus: done\nTotal: 1\nDone: 1\nFailed: 0\nPending: 0\nCreatedAt: \"\"\nUpdatedAt: \"\"\n",
+		},
+		{
+			name:        "not acceptable",
+			accept:      "text/html",
+			status:      http.StatusNotAcceptable,
+			contentType: MediaTypeProblemJSON,
+			body:        `{"type":"about:blank","title":"Not Acceptable","status":406,"detail":"Not acceptable, supported media types are application/json, application/xml, application/yaml, application/msgpack","instance":"/test","code":"not_acceptable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept", tt.accept)
+			ctx.Reset(w, req)
+
+			controller := web.Controller{}
+			controller.Init(ctx, "", "", nil)
+			controller.Ctx.Output.Header("Vary", "Accept-Language")
+
+			sendNegotiated(&controller, data, http.StatusOK)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept-Language, Accept", w.Header().Get("Vary"))
+			assert.Equal(t, tt.body, w.Body.String())
+		})
+	}
+}
+
+func TestSendErrorCodeResponseNegotiated(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "YAML",
+			accept:      "application/yaml",
+			contentType: "application/yaml; charset=utf-8",
+			body:        "type: \"about:blank\"\ntitle: Not Found\nstatus: 404\ndetail: Property not found\ninstance: \"/test\"\ncode: property_not_found\n",
+		},
+		{
+			name:        "unsupported type falls back to problem+json",
+			accept:      "text/html",
+			contentType: MediaTypeProblemJSON,
+			body:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"Property not found","instance":"/test","code":"property_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept", tt.accept)
+			ctx.Reset(w, req)
+
+			controller := web.Controller{}
+			controller.Init(ctx, "", "", nil)
+
+			SendErrorCodeResponse(&controller, structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"}, http.StatusNotFound)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, w.Body.String())
+		})
+	}
+}
@@ -72,38 +72,55 @@ func TestSendPropertyDetailsResponse(t *testing.T) {
 func TestSendErrorResponse(t *testing.T) {
 	tests := []struct {
 		name           string
+		code           string
 		message        string
 		status         int
 		expectedStatus int
-		expectedBody   string
+		expectedBody   structs.Problem
 	}{
 		{
 			name:           "Not Found Error",
+			code:           "property_not_found",
 			message:        "Property not found",
 			status:         http.StatusNotFound,
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "Property not found",
+			expectedBody: structs.Problem{
+				Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound,
+				Detail: "Property not found", Instance: "/test", Code: "property_not_found",
+			},
 		},
 		{
 			name:           "Bad Request Error",
-			message:        "Invalid property ID",
+			code:           "missing_parameter",
+			message:        "Property ID not provided",
 			status:         http.StatusBadRequest,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid property ID",
+			expectedBody: structs.Problem{
+				Type: "about:blank", Title: "Bad Request", Status: http.StatusBadRequest,
+				Detail: "Property ID not provided", Instance: "/test", Code: "missing_parameter",
+			},
 		},
 		{
 			name:           "Internal Server Error",
+			code:           "internal_error",
 			message:        "Failed to fetch property details",
 			status:         http.StatusInternalServerError,
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "Failed to fetch property details",
+			expectedBody: structs.Problem{
+				Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError,
+				Detail: "Failed to fetch property details", Instance: "/test", Code: "internal_error",
+			},
 		},
 		{
-			name:           "Empty Error Message",
-			message:        "",
-			status:         http.StatusBadRequest,
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "",
+			name:           "Non-standard status",
+			code:           "canceled",
+			message:        "Request canceled",
+			status:         499,
+			expectedStatus: 499,
+			expectedBody: structs.Problem{
+				Type: "about:blank", Title: "Request canceled", Status: 499,
+				Detail: "Request canceled", Instance: "/test", Code: "canceled",
+			},
 		},
 	}
 
@@ -119,11 +136,15 @@ func TestSendErrorResponse(t *testing.T) {
 			controller.Init(context, "", "", nil)
 
 			// Call the function
-			SendErrorResponse(&controller, tt.message, tt.status)
+			SendErrorResponse(&controller, tt.code, tt.message, tt.status)
 
 			// Assert response status and body
 			assert.Equal(t, tt.expectedStatus, w.Code)
-			assert.Equal(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, MediaTypeProblemJSON, w.Header().Get("Content-Type"))
+
+			var response structs.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedBody, response)
 		})
 	}
 }
@@ -337,3 +358,22 @@ func getMinimalPropertyDetails() structs.PropertyDetailsResponse {
 		},
 	}
 }
+
+func TestSendPropertyFieldsResponse(t *testing.T) {
+	input := map[string]interface{}{
+		"ID":       "123",
+		"Property": map[string]interface{}{"PropertyName": "Sea View", "Price": json.Number("150")},
+	}
+
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, httptest.NewRequest("GET", "/test?fields=ID,Property.PropertyName,Property.Price", nil))
+
+	controller := web.Controller{}
+	controller.Init(ctx, "", "", nil)
+
+	SendPropertyFieldsResponse(&controller, input)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"ID": "123", "Property": {"PropertyName": "Sea View", "Price": 150}}`, w.Body.String())
+}
@@ -0,0 +1,12 @@
+package responses
+
+import (
+	"beego-api-service/structs"
+	"net/http"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+func SendPropertyFullResponse(c *web.Controller, data structs.PropertyFullResponse) {
+	sendNegotiated(c, data, http.StatusOK)
+}
@@ -0,0 +1,74 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendPropertyFullResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          structs.PropertyFullResponse
+		expectedStatus int
+	}{
+		{
+			name: "Complete property",
+			input: structs.PropertyFullResponse{
+				Details:   getCompletePropertyDetails(),
+				OSDetails: getMinimalPropertyDetails(),
+				Images: structs.ImagesResponse{
+					"kitchen": []string{"https://example.com/kitchen.jpg"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Sections that could not be built",
+			input: structs.PropertyFullResponse{
+				Details: getCompletePropertyDetails(),
+				Images:  structs.ImagesResponse{},
+				Errors: map[string]*structs.ErrorResponse{
+					"OSDetails": {Code: "bad_upstream_payload", Message: "Upstream returned an invalid OS block"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Empty property",
+			input:          structs.PropertyFullResponse{},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create a test context
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, httptest.NewRequest("GET", "/test", nil))
+
+			// Create a test controller
+			controller := web.Controller{}
+			controller.Init(ctx, "", "", nil)
+
+			// Call the function
+			SendPropertyFullResponse(&controller, tt.input)
+
+			// Assert response status and body
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response structs.PropertyFullResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.input, response)
+		})
+	}
+}
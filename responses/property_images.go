@@ -1,7 +1,6 @@
 package responses
 
 import (
-	"log"
 	"net/http"
 
 	"beego-api-service/structs"
@@ -10,12 +9,5 @@ import (
 )
 
 func SendImagesResponse(c *web.Controller, data structs.ImagesResponse) {
-	c.Data["json"] = data
-	if err := c.ServeJSON(); err != nil {
-		log.Printf("Failed to serve JSON response: %v", err)
-		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
-		if writeErr := c.Ctx.Output.Body([]byte("Failed to serve JSON response")); writeErr != nil {
-			log.Printf("Failed to write error response: %v", writeErr)
-		}
-	}
+	sendNegotiated(c, data, http.StatusOK)
 }
@@ -0,0 +1,50 @@
+package responses
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// RequestIDHeader carries the ID of a request, in the request when a client
+// or proxy already assigned one and in every response.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the input data of the context.
+const requestIDKey = "RequestID"
+
+// validRequestID limits the IDs taken from clients to ones that are safe to
+// log and echo.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// AssignRequestID is a filter giving each request an ID: the X-Request-ID
+// header of the request if it is usable, otherwise a random one. The ID is
+// sent back in the X-Request-ID response header and in error bodies.
+func AssignRequestID(ctx *context.Context) {
+	id := ctx.Input.Header(RequestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = newRequestID()
+	}
+	ctx.Input.SetData(requestIDKey, id)
+	ctx.Output.Header(RequestIDHeader, id)
+}
+
+// requestID returns the ID AssignRequestID gave the request, or "" when the
+// filter did not run.
+func requestID(c *web.Controller) string {
+	id, _ := c.Ctx.Input.GetData(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id)
+}
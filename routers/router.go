@@ -2,17 +2,28 @@ package routers
 
 import (
 	"beego-api-service/controllers"
+	"beego-api-service/responses"
 
 	"github.com/beego/beego/v2/server/web"
 )
 
 func init() {
+	web.InsertFilter("/*", web.BeforeRouter, responses.AssignRequestID)
+
 	ns := web.NewNamespace("/v1/api",
 		web.NSNamespace("/property",
 			web.NSRouter("/details/:propertyId", &controllers.PropertyDetailsController{}, "get:GetPropertyDetails"),
 			web.NSRouter("/gallery/:propertyId", &controllers.PropertyImagesController{}, "get:GetPropertyImages"),
+			web.NSRouter("/:propertyId/full", &controllers.PropertyFullController{}, "get:GetPropertyFull"),
+			web.NSRouter("/:propertyId/discrepancies", &controllers.PropertyDiscrepanciesController{}, "get:GetPropertyDiscrepancies"),
+		),
+		web.NSRouter("/propertyList", &controllers.BulkPropertyFetchController{}, "get:BulkPropertyFetch;post:PostBulkPropertyFetch"),
+		web.NSRouter("/propertyList/discrepancies", &controllers.PropertyDiscrepanciesController{}, "get:ReconcileProperties"),
+		web.NSNamespace("/jobs",
+			web.NSRouter("/property-fetch", &controllers.PropertyFetchJobsController{}, "post:CreateJob"),
+			web.NSRouter("/:id", &controllers.PropertyFetchJobsController{}, "get:GetJob;delete:CancelJob"),
+			web.NSRouter("/:id/results", &controllers.PropertyFetchJobsController{}, "get:GetJobResults"),
 		),
-		web.NSRouter("/propertyList", &controllers.BulkPropertyFetchController{}, "get:BulkPropertyFetch"),
 	)
 
 	web.AddNamespace(ns)
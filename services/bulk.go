@@ -0,0 +1,67 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"context"
+	"sync"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// ForEachProperty calls fetch for every ID on at most bulkConcurrency
+// workers and returns once all started calls have finished. When ctx is
+// done, e.g. because the client disconnected, the remaining IDs are skipped.
+func ForEachProperty(ctx context.Context, ids []string, fetch func(i int, id string)) {
+	forEachProperty(ctx, ids, web.AppConfig.DefaultInt("bulkConcurrency", 10), fetch)
+}
+
+func forEachProperty(ctx context.Context, ids []string, workers int, fetch func(i int, id string)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(ids) {
+		workers = len(ids)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				fetch(i, ids[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range ids {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// SummarizeBulkItems counts the items of a bulk response by status.
+func SummarizeBulkItems(items []structs.BulkItem) structs.BulkPropertyResponse {
+	summary := structs.BulkSummary{Total: len(items)}
+	for _, item := range items {
+		switch item.Status {
+		case structs.BulkItemOK:
+			summary.OK++
+		case structs.BulkItemNotFound:
+			summary.NotFound++
+		default:
+			summary.Error++
+		}
+	}
+	return structs.BulkPropertyResponse{Summary: summary, Items: items}
+}
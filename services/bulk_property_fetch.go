@@ -2,56 +2,28 @@ package services
 
 import (
 	"beego-api-service/structs"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
-
-	"github.com/beego/beego/v2/server/web"
 )
 
-func FetchOSPropertyDetails(propertyId string) (structs.PropertyDetailsResponse, error) {
-	var transformedData structs.PropertyDetailsResponse
-
-	// Load the external API base URL from the configuration
-	externalAPIBaseURL, err := web.AppConfig.String("externalAPIBaseURL")
-	if err != nil {
-		log.Printf("failed to load external API URL from config: %v", err)
-		return transformedData, err
-	}
-
-	// Construct the external API URL
-	externalAPIURL := fmt.Sprintf("%s?propertyId=%s&languageCode=en", externalAPIBaseURL, propertyId)
-	resp, err := http.Get(externalAPIURL)
-	if err != nil {
-		log.Printf("HTTP request failed: %v", err)
-		return transformedData, err
-	}
-	defer resp.Body.Close()
-
-	// Decode the JSON response
-	var originalData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&originalData); err != nil {
-		log.Printf("failed to decode response: %v", err)
-		return transformedData, err
-	}
-
-	// Transform the original data into the desired format
-	if err := transformOSData(originalData, &transformedData); err != nil {
-		log.Printf("failed to transform data: %v", err)
-		return transformedData, err
-	}
-
-	return transformedData, nil
+func FetchOSPropertyDetails(ctx context.Context, propertyId string, opts FetchOptions) (structs.PropertyDetailsResponse, FetchMeta, error) {
+	// Build the details from the OS block unless the client chose another source
+	return fetchDetails(ctx, propertyId, opts, SourceOS, nil)
 }
 
+// transformOSData builds the details from the OS block. Missing fields are
+// left empty; values of the wrong type are reported in a *ValidationError.
 func transformOSData(originalData map[string]interface{}, transformedData *structs.PropertyDetailsResponse) error {
 	osData, ok := originalData["OS"].(map[string]interface{})
 	if !ok {
 		return fmt.Errorf("invalid OS data")
 	}
 
+	var d documentDecoder
+
 	if id, ok := osData["id"].(string); ok {
 		transformedData.ID = id
 	}
@@ -64,7 +36,7 @@ func transformOSData(originalData map[string]interface{}, transformedData *struc
 		transformedData.Published = published
 	}
 
-	if err := parseCategories(osData, transformedData); err != nil {
+	if err := parseCategories(&d, osData, transformedData); err != nil {
 		return err
 	}
 
@@ -94,8 +66,18 @@ func transformOSData(originalData map[string]interface{}, transformedData *struc
 
 	if lonlat, ok := osData["lonlat"].(map[string]interface{}); ok {
 		if coordinates, ok := lonlat["coordinates"].([]interface{}); ok && len(coordinates) >= 2 {
-			transformedData.GeoInfo.Lat = fmt.Sprintf("%f", coordinates[1].(float64))
-			transformedData.GeoInfo.Lng = fmt.Sprintf("%f", coordinates[0].(float64))
+			lng, lngOK := coordinates[0].(float64)
+			lat, latOK := coordinates[1].(float64)
+			if !lngOK {
+				d.fail("OS.lonlat.coordinates[0]", "must be a number, got %s", jsonType(coordinates[0]))
+			}
+			if !latOK {
+				d.fail("OS.lonlat.coordinates[1]", "must be a number, got %s", jsonType(coordinates[1]))
+			}
+			if lngOK && latOK {
+				transformedData.GeoInfo.Lat = fmt.Sprintf("%f", lat)
+				transformedData.GeoInfo.Lng = fmt.Sprintf("%f", lng)
+			}
 		}
 	}
 
@@ -103,7 +85,12 @@ func transformOSData(originalData map[string]interface{}, transformedData *struc
 		amenities := map[string]string{}
 		if amenitiesList, ok := osData["amenity_categories"].([]interface{}); ok {
 			for i, amenity := range amenitiesList {
-				amenities[fmt.Sprintf("%d", i+1)] = amenity.(string)
+				name, ok := amenity.(string)
+				if !ok {
+					d.fail(fmt.Sprintf("OS.amenity_categories[%d]", i), "must be a string, got %s", jsonType(amenity))
+					continue
+				}
+				amenities[fmt.Sprintf("%d", i+1)] = name
 			}
 		}
 		return amenities
@@ -207,17 +194,24 @@ func transformOSData(originalData map[string]interface{}, transformedData *struc
 		transformedData.Partner.EpCluster = clusterID
 	}
 
-	return nil
+	return d.Err()
 }
 
-func parseCategories(osData map[string]interface{}, transformedData *structs.PropertyDetailsResponse) error {
+// parseCategories decodes the JSON encoded categories of the OS block. As in
+// the S3 block, every category needs a Name.
+func parseCategories(d *documentDecoder, osData map[string]interface{}, transformedData *structs.PropertyDetailsResponse) error {
 	if categoriesJSON, ok := osData["categories"].(string); ok {
 		var categories []map[string]interface{}
 		if err := json.Unmarshal([]byte(categoriesJSON), &categories); err != nil {
 			log.Printf("failed to unmarshal categories: %v", err)
 			return errors.New("failed to unmarshal categories")
 		}
-		for _, category := range categories {
+		for i, fields := range categories {
+			category := docNode{path: fmt.Sprintf("OS.categories[%d]", i), fields: fields}
+			display := d.Strings(category, "Display", optional)
+			if display == nil {
+				display = []string{}
+			}
 			transformedData.GeoInfo.Categories = append(transformedData.GeoInfo.Categories, struct {
 				Name       string   `json:"Name"`
 				Slug       string   `json:"Slug"`
@@ -225,19 +219,11 @@ func parseCategories(osData map[string]interface{}, transformedData *structs.Pro
 				Display    []string `json:"Display"`
 				LocationID string   `json:"LocationID"`
 			}{
-				Name: category["Name"].(string),
-				Slug: category["Slug"].(string),
-				Type: category["Type"].(string),
-				Display: func() []string {
-					display := []string{}
-					if d, ok := category["Display"].([]interface{}); ok {
-						for _, v := range d {
-							display = append(display, v.(string))
-						}
-					}
-					return display
-				}(),
-				LocationID: category["LocationID"].(string),
+				Name:       d.String(category, "Name", required),
+				Slug:       d.String(category, "Slug", optional),
+				Type:       d.String(category, "Type", optional),
+				Display:    display,
+				LocationID: d.String(category, "LocationID", optional),
 			})
 		}
 	}
@@ -1,9 +1,10 @@
 package services
 
 import (
+	"beego-api-service/internal/fakeupstream"
 	"beego-api-service/structs"
-	"net/http"
-	"net/http/httptest"
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/beego/beego/v2/server/web"
@@ -14,69 +15,13 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 	tests := []struct {
 		name           string
 		propertyID     string
-		mockResponse   string
+		scenario       fakeupstream.Scenario
 		expectedResult structs.PropertyDetailsResponse
 		expectError    bool
 	}{
 		{
 			name:       "Success with complete data",
 			propertyID: "HA-3213808988",
-			mockResponse: `{
-                "OS": {
-                    "amenity_categories": [
-                        "Air Conditioner",
-                        "Balcony/Terrace",
-                        "Bedding/Linens",
-                        "Child Friendly",
-                        "Kitchen",
-                        "Laundry",
-                        "Pool",
-                        "View",
-                        "Ocean View",
-                        "Sports/Activities",
-                        "Wellness Facilities",
-                        "Spa",
-                        "Guest Services",
-                        "Entertainment"
-                    ],
-                    "archived": ["VRBO", "EP", "HC"],
-                    "bathroom_count": 3,
-                    "bedroom_count": 3,
-                    "brand_id": "321",
-                    "categories": "[{\"LocationID\": \"117\", \"Name\": \"Mexico\", \"Type\": \"country\", \"Slug\": \"mexico\", \"Display\": [\"mexico\"]}, {\"LocationID\": \"11116\", \"Name\": \"Baja California Sur\", \"Type\": \"state\", \"Slug\": \"mexico/baja-california-sur\", \"Display\": [\"mexico\", \"baja-california-sur\"]}, {\"LocationID\": \"180032\", \"Name\": \"Los Cabos\", \"Type\": \"region\", \"Slug\": \"mexico/baja-california-sur/los-cabos\", \"Display\": [\"mexico\", \"baja-california-sur\", \"los-cabos\"]}, {\"LocationID\": \"6349690\", \"Name\": \"El Tezal\", \"Type\": \"city\", \"Slug\": \"mexico/baja-california-sur/cabo-san-lucas/el-tezal\", \"Display\": [\"mexico\", \"baja-california-sur\", \"cabo-san-lucas\", \"el-tezal\"]}]",
-                    "city": "El Tezal",
-                    "cluster_id": "c002",
-                    "country": "Mexico",
-                    "country_code": "MX",
-                    "display": "El Tezal, Cabo San Lucas, Baja California Sur, Mexico",
-                    "feature_image": "https://images.trvl-media.com/lodging/102000000/101740000/101739900/101739817/7c032e5f.jpg?impolicy=fcrop&w=1000&h=666&quality=medium",
-                    "feed": 12,
-                    "feed_provider_url": "https://www.vrbo.com/search?selected=101739817&regionId=6349690",
-                    "hcom_id": "3256674144",
-                    "id": "HA-3213808988",
-                    "location_id": "6349690",
-                    "lonlat": {
-                        "coordinates": [-109.88175, 22.907337]
-                    },
-                    "min_stay": 1,
-                    "number_of_review": 1,
-                    "occupancy": 8,
-                    "owner_id": "101739817",
-                    "property_flags": {
-                        "eco_friendly": false
-                    },
-                    "property_name": "Brand New Luxury Penthouse w/Jacuzzi",
-                    "property_slug": "brand-new-luxury-penthouse-w-jacuzzi",
-                    "property_type": "Apartment",
-                    "property_type_category": "Apartment",
-                    "published": true,
-                    "review_score_general": 5,
-                    "room_size_sqft": 3121,
-                    "unit_number": "4383133",
-                    "updated_at": "2024-05-03T11:46:19.189256+00:00",
-                    "usd_price": 170
-                }
-            }`,
 			expectedResult: structs.PropertyDetailsResponse{
 				ID:        "HA-3213808988",
 				Feed:      12,
@@ -112,20 +57,6 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 							Display:    []string{"mexico"},
 							LocationID: "117",
 						},
-						{
-							Name:       "Baja California Sur",
-							Slug:       "mexico/baja-california-sur",
-							Type:       "state",
-							Display:    []string{"mexico", "baja-california-sur"},
-							LocationID: "11116",
-						},
-						{
-							Name:       "Los Cabos",
-							Slug:       "mexico/baja-california-sur/los-cabos",
-							Type:       "region",
-							Display:    []string{"mexico", "baja-california-sur", "los-cabos"},
-							LocationID: "180032",
-						},
 						{
 							Name:       "El Tezal",
 							Slug:       "mexico/baja-california-sur/cabo-san-lucas/el-tezal",
@@ -169,20 +100,11 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 					UpdatedAt              string             `json:"UpdatedAt"`
 				}{
 					Amenities: map[string]string{
-						"1":  "Air Conditioner",
-						"2":  "Balcony/Terrace",
-						"3":  "Bedding/Linens",
-						"4":  "Child Friendly",
-						"5":  "Kitchen",
-						"6":  "Laundry",
-						"7":  "Pool",
-						"8":  "View",
-						"9":  "Ocean View",
-						"10": "Sports/Activities",
-						"11": "Wellness Facilities",
-						"12": "Spa",
-						"13": "Guest Services",
-						"14": "Entertainment",
+						"1": "Air Conditioner",
+						"2": "Balcony/Terrace",
+						"3": "Kitchen",
+						"4": "Pool",
+						"5": "Ocean View",
 					},
 					Counts: struct {
 						Bedroom   int `json:"Bedroom"`
@@ -196,7 +118,7 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 						Occupancy: 8,
 					},
 					EcoFriendly:            false,
-					FeatureImage:           "https://images.trvl-media.com/lodging/102000000/101740000/101739900/101739817/7c032e5f.jpg?impolicy=fcrop&w=1000&h=666&quality=medium",
+					FeatureImage:           "https://images.example.com/HA-3213808988/feature.jpg",
 					Price:                  170,
 					PropertyName:           "Brand New Luxury Penthouse w/Jacuzzi",
 					PropertySlug:           "brand-new-luxury-penthouse-w-jacuzzi",
@@ -230,37 +152,24 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name:       "Invalid OS data structure",
-			propertyID: "INVALID-ID",
-			mockResponse: `{
-                "OS": "invalid"
-            }`,
-			expectedResult: structs.PropertyDetailsResponse{},
-			expectError:    true,
+			name:        "Invalid OS data structure",
+			propertyID:  "TEST-OS-INVALID",
+			expectError: true,
 		},
 		{
-			name:         "Invalid JSON response",
-			propertyID:   "ERROR-ID",
-			mockResponse: `invalid json`,
-			expectError:  true,
+			name:        "Invalid JSON response",
+			propertyID:  "HA-3213808988",
+			scenario:    fakeupstream.Scenario{TruncateRate: 1},
+			expectError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				assert.Equal(t, "GET", r.Method)
-				assert.Contains(t, r.URL.String(), tt.propertyID)
-				assert.Contains(t, r.URL.String(), "languageCode=en")
+			_, url := startFakeUpstream(t, tt.scenario)
+			web.AppConfig.Set("externalAPIBaseURL", url)
 
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(tt.mockResponse))
-			}))
-			defer server.Close()
-
-			web.AppConfig.Set("externalAPIBaseURL", server.URL)
-
-			result, err := FetchOSPropertyDetails(tt.propertyID)
+			result, _, err := FetchOSPropertyDetails(context.Background(), tt.propertyID, FetchOptions{})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -275,7 +184,7 @@ func TestFetchOSPropertyDetails(t *testing.T) {
 func TestFetchOSPropertyDetailsHTTPError(t *testing.T) {
 	web.AppConfig.Set("externalAPIBaseURL", "http://invalid-url-that-will-fail")
 
-	result, err := FetchOSPropertyDetails("PROP123")
+	result, _, err := FetchOSPropertyDetails(context.Background(), "PROP123", FetchOptions{})
 
 	assert.Error(t, err)
 	assert.Empty(t, result)
@@ -284,8 +193,57 @@ func TestFetchOSPropertyDetailsHTTPError(t *testing.T) {
 func TestFetchOSPropertyDetailsConfigError(t *testing.T) {
 	web.AppConfig.Set("externalAPIBaseURL", "")
 
-	result, err := FetchOSPropertyDetails("PROP123")
+	result, _, err := FetchOSPropertyDetails(context.Background(), "PROP123", FetchOptions{})
 
 	assert.Error(t, err)
 	assert.Empty(t, result)
 }
+
+func TestTransformOSDataInvalidValues(t *testing.T) {
+	tests := []struct {
+		name           string
+		osData         map[string]interface{}
+		expectedFields []string
+	}{
+		{
+			name:           "Category without a string Name",
+			osData:         map[string]interface{}{"categories": `[{"Name":"Mexico"},{"Slug":"cancun"},{"Name":1,"Display":["a",2]}]`},
+			expectedFields: []string{"OS.categories[1].Name", "OS.categories[2].Display[1]", "OS.categories[2].Name"},
+		},
+		{
+			name:           "Coordinates that are not numbers",
+			osData:         map[string]interface{}{"lonlat": map[string]interface{}{"coordinates": []interface{}{"x", "y"}}},
+			expectedFields: []string{"OS.lonlat.coordinates[0]", "OS.lonlat.coordinates[1]"},
+		},
+		{
+			name:           "Amenity that is not a string",
+			osData:         map[string]interface{}{"amenity_categories": []interface{}{"Pool", float64(3)}},
+			expectedFields: []string{"OS.amenity_categories[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result structs.PropertyDetailsResponse
+			err := transformOSData(map[string]interface{}{"OS": tt.osData}, &result)
+
+			var validationErr *ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			var paths []string
+			for _, field := range validationErr.Fields {
+				paths = append(paths, field.Path)
+			}
+			assert.Equal(t, tt.expectedFields, paths)
+		})
+	}
+}
+
+func TestFetchOSPropertyDetailsInvalidValues(t *testing.T) {
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{})
+	web.AppConfig.Set("externalAPIBaseURL", url)
+
+	_, _, err := FetchOSPropertyDetails(context.Background(), "TEST-OS-BAD", FetchOptions{})
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrorBadUpstreamPayload, ErrorCodeOf(err))
+}
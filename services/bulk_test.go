@@ -0,0 +1,70 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachProperty(t *testing.T) {
+	web.AppConfig.Set("bulkConcurrency", "3")
+	defer web.AppConfig.Set("bulkConcurrency", "10")
+
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	var running, maxRunning int32
+	var mu sync.Mutex
+	seen := make([]string, len(ids))
+
+	ForEachProperty(context.Background(), ids, func(i int, id string) {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		if now > maxRunning {
+			maxRunning = now
+		}
+		seen[i] = id
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	assert.Equal(t, ids, seen)
+	assert.Equal(t, int32(3), maxRunning)
+}
+
+func TestForEachPropertyStopsWhenCanceled(t *testing.T) {
+	web.AppConfig.Set("bulkConcurrency", "2")
+	defer web.AppConfig.Set("bulkConcurrency", "10")
+
+	ids := make([]string, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+
+	ForEachProperty(ctx, ids, func(i int, id string) {
+		if atomic.AddInt32(&calls, 1) == 4 {
+			cancel()
+		}
+		time.Sleep(time.Millisecond)
+	})
+
+	assert.True(t, atomic.LoadInt32(&calls) <= 6)
+}
+
+func TestSummarizeBulkItems(t *testing.T) {
+	items := []structs.BulkItem{
+		{ID: "HA-1", Status: structs.BulkItemOK},
+		{ID: "HA-2", Status: structs.BulkItemNotFound},
+		{ID: "HA-3", Status: structs.BulkItemError},
+		{ID: "HA-4", Status: structs.BulkItemOK},
+	}
+
+	response := SummarizeBulkItems(items)
+
+	assert.Equal(t, structs.BulkSummary{Total: 4, OK: 2, NotFound: 1, Error: 1}, response.Summary)
+	assert.Equal(t, items, response.Items)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker guarding the upstream API.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// circuitBreaker stops calling the upstream once too many recent requests
+// failed. After coolDown it lets a limited number of probe requests through
+// (half-open); a successful probe closes the circuit, a failed one reopens it.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	failureRate    float64
+	minRequests    int
+	window         time.Duration
+	coolDown       time.Duration
+	halfOpenProbes int
+
+	state       BreakerState
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int
+	now         func() time.Time
+}
+
+func newCircuitBreaker(failureRate float64, minRequests int, window, coolDown time.Duration, halfOpenProbes int) *circuitBreaker {
+	if halfOpenProbes < 1 {
+		halfOpenProbes = 1
+	}
+	return &circuitBreaker{
+		failureRate:    failureRate,
+		minRequests:    minRequests,
+		window:         window,
+		coolDown:       coolDown,
+		halfOpenProbes: halfOpenProbes,
+		state:          BreakerClosed,
+		now:            time.Now,
+	}
+}
+
+// State returns the current breaker state.
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow reports whether a request may be sent upstream. When it may not, the
+// returned duration tells the caller how long until the breaker is retried.
+func (b *circuitBreaker) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	switch b.state {
+	case BreakerOpen:
+		return false, b.coolDown - b.now().Sub(b.openedAt)
+	case BreakerHalfOpen:
+		if b.probes >= b.halfOpenProbes {
+			return false, b.coolDown
+		}
+		b.probes++
+	}
+	return true, 0
+}
+
+// Record reports the outcome of a request that Allow let through.
+func (b *circuitBreaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.trip()
+			return
+		}
+		b.reset(BreakerClosed)
+	case BreakerClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.minRequests && float64(b.failures)/float64(b.requests) >= b.failureRate {
+			b.trip()
+		}
+	}
+}
+
+// Release returns a half-open probe slot taken by Allow for a request whose
+// outcome is unknown, e.g. because the caller cancelled it.
+func (b *circuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// advance moves an open breaker to half-open once the cool-down has passed
+// and starts a new counting window when the current one has elapsed.
+func (b *circuitBreaker) advance() {
+	now := b.now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.coolDown {
+			b.state = BreakerHalfOpen
+			b.probes = 0
+		}
+	case BreakerClosed:
+		if b.window > 0 && now.Sub(b.windowStart) > b.window {
+			b.requests = 0
+			b.failures = 0
+			b.windowStart = now
+		}
+	}
+}
+
+func (b *circuitBreaker) trip() {
+	b.reset(BreakerOpen)
+	b.openedAt = b.now()
+}
+
+func (b *circuitBreaker) reset(state BreakerState) {
+	b.state = state
+	b.requests = 0
+	b.failures = 0
+	b.probes = 0
+	b.windowStart = b.now()
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 9, 8, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(0.5, 4, time.Minute, 30*time.Second, 1)
+	breaker.now = func() time.Time { return now }
+
+	// Failures below the minimum request volume keep the circuit closed
+	for i := 0; i < 3; i++ {
+		allowed, _ := breaker.Allow()
+		assert.True(t, allowed)
+		breaker.Record(true)
+	}
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// Reaching the failure rate opens it
+	breaker.Allow()
+	breaker.Record(false)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	allowed, retryAfter := breaker.Allow()
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// After the cool-down a single probe is let through
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	allowed, _ = breaker.Allow()
+	assert.True(t, allowed)
+	allowed, _ = breaker.Allow()
+	assert.False(t, allowed)
+
+	// A failed probe reopens the circuit
+	breaker.Record(true)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// A successful probe closes it again
+	now = now.Add(31 * time.Second)
+	allowed, _ = breaker.Allow()
+	assert.True(t, allowed)
+	breaker.Record(false)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Date(2025, 1, 9, 8, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(0.5, 2, time.Minute, 30*time.Second, 1)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Allow()
+	breaker.Record(true)
+
+	// The earlier failure falls out of the window and no longer counts
+	now = now.Add(2 * time.Minute)
+	breaker.Allow()
+	breaker.Record(true)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestUpstreamClientCircuitBreaker(t *testing.T) {
+	upstream, url := startFakeUpstream(t, fakeupstream.Scenario{
+		ErrorRate:   1,
+		ErrorStatus: http.StatusBadGateway,
+		NotFoundIDs: []string{"missing"},
+	})
+
+	client := NewUpstreamClient(url, time.Second)
+	client.breaker = newCircuitBreaker(0.5, 2, time.Minute, 30*time.Second, 1)
+
+	// Not-found responses do not count as upstream failures
+	for i := 0; i < 2; i++ {
+		_, _, err := client.FetchPropertyDocument(context.Background(), "missing", []string{"en"})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerClosed, client.breaker.State())
+
+	for i := 0; i < 2; i++ {
+		_, _, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerOpen, client.breaker.State())
+	assert.Equal(t, 4, upstream.Requests())
+
+	// While open, requests fail fast without reaching the upstream
+	_, _, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+	var upstreamErr *UpstreamError
+	assert.True(t, errors.As(err, &upstreamErr))
+	assert.Equal(t, UpstreamErrorCircuitOpen, upstreamErr.Kind)
+	assert.Greater(t, upstreamErr.RetryAfter, time.Duration(0))
+	assert.Equal(t, 4, upstream.Requests())
+}
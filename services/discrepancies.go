@@ -0,0 +1,97 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"context"
+	"log"
+	"reflect"
+	"strconv"
+)
+
+// FetchPropertyDiscrepancies builds the S3 and OS views from one upstream
+// document and reports every field on which they disagree.
+func FetchPropertyDiscrepancies(ctx context.Context, propertyId string, opts FetchOptions) (structs.DiscrepancyReport, FetchMeta, error) {
+	report := structs.DiscrepancyReport{ID: propertyId, Discrepancies: []structs.FieldDiscrepancy{}}
+
+	originalData, meta, err := fetchDocument(ctx, propertyId, opts)
+	if err != nil {
+		return report, meta, err
+	}
+
+	var s3Details, osDetails structs.PropertyDetailsResponse
+	if err := transformData(originalData, &s3Details); err != nil {
+		log.Printf("failed to transform S3 data: %v", err)
+		return report, meta, newPayloadError(propertyId, err)
+	}
+
+	if err := transformOSData(originalData, &osDetails); err != nil {
+		log.Printf("failed to transform OS data: %v", err)
+		return report, meta, newPayloadError(propertyId, err)
+	}
+
+	report.Discrepancies = diffDetails(s3Details, osDetails)
+	return report, meta, nil
+}
+
+// ReconcileReports summarises per-property discrepancy reports.
+func ReconcileReports(reports []structs.DiscrepancyReport) structs.ReconciliationReport {
+	summary := structs.ReconciliationSummary{Total: len(reports), Fields: map[string]int{}}
+	for _, report := range reports {
+		switch {
+		case report.Error != nil:
+			summary.Failed++
+		case len(report.Discrepancies) == 0:
+			summary.Consistent++
+		default:
+			summary.WithDiscrepancies++
+		}
+		for _, discrepancy := range report.Discrepancies {
+			summary.Fields[discrepancy.Field]++
+		}
+	}
+	return structs.ReconciliationReport{Properties: reports, Summary: summary}
+}
+
+// diffDetails compares two views of a property leaf field by leaf field, in
+// struct order.
+func diffDetails(s3Details, osDetails structs.PropertyDetailsResponse) []structs.FieldDiscrepancy {
+	discrepancies := []structs.FieldDiscrepancy{}
+	diffValue(reflect.ValueOf(s3Details), reflect.ValueOf(osDetails), "", &discrepancies)
+	return discrepancies
+}
+
+func diffValue(s3Value, osValue reflect.Value, path string, discrepancies *[]structs.FieldDiscrepancy) {
+	if s3Value.Kind() == reflect.Struct {
+		for i := 0; i < s3Value.NumField(); i++ {
+			diffValue(s3Value.Field(i), osValue.Field(i), joinPath(path, s3Value.Type().Field(i).Name), discrepancies)
+		}
+		return
+	}
+
+	if !sameValue(s3Value, osValue) {
+		*discrepancies = append(*discrepancies, structs.FieldDiscrepancy{
+			Field: path,
+			S3:    s3Value.Interface(),
+			OS:    osValue.Interface(),
+		})
+	}
+}
+
+// sameValue treats nil and empty collections as equal and compares strings
+// holding numbers by value, since the OS block formats coordinates
+// differently.
+func sameValue(s3Value, osValue reflect.Value) bool {
+	switch s3Value.Kind() {
+	case reflect.Slice, reflect.Map:
+		if s3Value.Len() == 0 && osValue.Len() == 0 {
+			return true
+		}
+	case reflect.String:
+		s3Number, s3Err := strconv.ParseFloat(s3Value.String(), 64)
+		osNumber, osErr := strconv.ParseFloat(osValue.String(), 64)
+		if s3Err == nil && osErr == nil {
+			return s3Number == osNumber
+		}
+	}
+	return reflect.DeepEqual(s3Value.Interface(), osValue.Interface())
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"beego-api-service/internal/fakeupstream"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDetails(t *testing.T) {
+	var s3Details, osDetails structs.PropertyDetailsResponse
+	s3Details.ID = "HA-1"
+	osDetails.ID = "HA-1"
+	s3Details.GeoInfo.Lat = "12.345"
+	osDetails.GeoInfo.Lat = "12.345000"
+	s3Details.Property.Price = 100
+	osDetails.Property.Price = 120
+	s3Details.Partner.Archived = []string{}
+	s3Details.Property.ReviewScore = 9
+
+	discrepancies := diffDetails(s3Details, osDetails)
+
+	assert.Equal(t, []structs.FieldDiscrepancy{
+		{Field: "Property.Price", S3: 100, OS: 120},
+		{Field: "Property.ReviewScore", S3: 9, OS: 0},
+	}, discrepancies)
+}
+
+func TestFetchPropertyDiscrepancies(t *testing.T) {
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{})
+	web.AppConfig.Set("externalAPIBaseURL", url)
+
+	report, _, err := FetchPropertyDiscrepancies(context.Background(), "TEST-MERGE", FetchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST-MERGE", report.ID)
+
+	fields := map[string]structs.FieldDiscrepancy{}
+	for _, discrepancy := range report.Discrepancies {
+		fields[discrepancy.Field] = discrepancy
+	}
+	assert.Equal(t, structs.FieldDiscrepancy{Field: "Property.Price", S3: 100, OS: 120}, fields["Property.Price"])
+	assert.Equal(t, structs.FieldDiscrepancy{Field: "GeoInfo.City", S3: "Test City", OS: "OS City"}, fields["GeoInfo.City"])
+	assert.NotContains(t, fields, "ID")
+
+	_, _, err = FetchPropertyDiscrepancies(context.Background(), "missing", FetchOptions{})
+	assert.Equal(t, ErrorNotFound, ErrorCodeOf(err))
+}
+
+func TestReconcileReports(t *testing.T) {
+	reports := []structs.DiscrepancyReport{
+		{ID: "1", Discrepancies: []structs.FieldDiscrepancy{{Field: "Property.Price"}, {Field: "GeoInfo.Lat"}}},
+		{ID: "2", Discrepancies: []structs.FieldDiscrepancy{{Field: "Property.Price"}}},
+		{ID: "3", Discrepancies: []structs.FieldDiscrepancy{}},
+		{ID: "4", Discrepancies: []structs.FieldDiscrepancy{}, Error: &structs.ErrorResponse{Code: string(ErrorNotFound)}},
+	}
+
+	report := ReconcileReports(reports)
+
+	assert.Equal(t, reports, report.Properties)
+	assert.Equal(t, structs.ReconciliationSummary{
+		Total:             4,
+		Consistent:        1,
+		WithDiscrepancies: 2,
+		Failed:            1,
+		Fields:            map[string]int{"Property.Price": 2, "GeoInfo.Lat": 1},
+	}, report.Summary)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheStatus is reported to clients in the X-Cache response header.
+type CacheStatus string
+
+const (
+	CacheBypass CacheStatus = "BYPASS"
+	CacheMiss   CacheStatus = "MISS"
+	CacheStale  CacheStatus = "STALE"
+	CacheHit    CacheStatus = "HIT"
+)
+
+// cacheStatusRank orders statuses from best to worst so that merged metadata
+// reports the least favourable outcome.
+var cacheStatusRank = map[CacheStatus]int{
+	CacheHit:    1,
+	CacheStale:  2,
+	CacheMiss:   3,
+	CacheBypass: 4,
+}
+
+// documentCache is an in-process LRU cache of upstream property documents.
+// Entries are fresh for ttl and may be served stale for a further staleTTL
+// while a single background refresh replaces them. Documents the upstream
+// does not have are remembered for missingTTL, so that language fallbacks do
+// not ask for them again on every request.
+type documentCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	staleTTL   time.Duration
+	missingTTL time.Duration
+	maxEntries int
+	entries    *list.List
+	items      map[string]*list.Element
+	refreshing map[string]bool
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	key      string
+	document map[string]interface{}
+	// missing is the error of a document the upstream does not have.
+	missing  error
+	storedAt time.Time
+}
+
+func newDocumentCache(ttl, staleTTL, missingTTL time.Duration, maxEntries int) *documentCache {
+	return &documentCache{
+		ttl:        ttl,
+		staleTTL:   staleTTL,
+		missingTTL: missingTTL,
+		maxEntries: maxEntries,
+		entries:    list.New(),
+		items:      make(map[string]*list.Element),
+		refreshing: make(map[string]bool),
+		now:        time.Now,
+	}
+}
+
+func documentCacheKey(propertyId, languageCode string) string {
+	return propertyId + "|" + languageCode
+}
+
+// Get returns the cached document for key with its freshness, or the error
+// recorded by SetMissing. Expired entries are removed and reported as a miss.
+func (c *documentCache) Get(key string) (map[string]interface{}, CacheStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, CacheMiss, nil
+	}
+
+	entry := element.Value.(*cacheEntry)
+	age := c.now().Sub(entry.storedAt)
+	if entry.missing != nil {
+		if age > c.missingTTL {
+			c.removeElement(element)
+			return nil, CacheMiss, nil
+		}
+		c.entries.MoveToFront(element)
+		return nil, CacheHit, entry.missing
+	}
+	if age > c.ttl+c.staleTTL {
+		c.removeElement(element)
+		return nil, CacheMiss, nil
+	}
+
+	c.entries.MoveToFront(element)
+	if age > c.ttl {
+		return entry.document, CacheStale, nil
+	}
+	return entry.document, CacheHit, nil
+}
+
+// Set stores document under key, evicting the least recently used entries
+// once maxEntries is exceeded.
+func (c *documentCache) Set(key string, document map[string]interface{}) {
+	c.store(key, document, nil)
+}
+
+// SetMissing records that the upstream answered err for key. It is ignored
+// when missingTTL is 0.
+func (c *documentCache) SetMissing(key string, err error) {
+	if c.missingTTL > 0 {
+		c.store(key, nil, err)
+	}
+}
+
+func (c *documentCache) store(key string, document map[string]interface{}, missing error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.document = document
+		entry.missing = missing
+		entry.storedAt = c.now()
+		c.entries.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.entries.PushFront(&cacheEntry{key: key, document: document, missing: missing, storedAt: c.now()})
+	for c.maxEntries > 0 && c.entries.Len() > c.maxEntries {
+		c.removeElement(c.entries.Back())
+	}
+}
+
+// Len returns the number of cached documents.
+func (c *documentCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.Len()
+}
+
+// beginRefresh reports whether the caller should refresh key. Only one
+// refresh per key runs at a time; finishRefresh releases it.
+func (c *documentCache) beginRefresh(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing[key] {
+		return false
+	}
+	c.refreshing[key] = true
+	return true
+}
+
+func (c *documentCache) finishRefresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refreshing, key)
+}
+
+func (c *documentCache) removeElement(element *list.Element) {
+	c.entries.Remove(element)
+	delete(c.items, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentCacheGet(t *testing.T) {
+	now := time.Date(2025, 1, 9, 8, 0, 0, 0, time.UTC)
+	cache := newDocumentCache(time.Minute, time.Minute, 0, 10)
+	cache.now = func() time.Time { return now }
+
+	document := map[string]interface{}{"S3": map[string]interface{}{"ID": "123"}}
+	cache.Set(documentCacheKey("123", "en"), document)
+
+	tests := []struct {
+		name           string
+		key            string
+		age            time.Duration
+		expectedStatus CacheStatus
+		expectDocument bool
+	}{
+		{name: "Fresh entry", key: documentCacheKey("123", "en"), age: 30 * time.Second, expectedStatus: CacheHit, expectDocument: true},
+		{name: "Other language", key: documentCacheKey("123", "fr"), age: 30 * time.Second, expectedStatus: CacheMiss, expectDocument: false},
+		{name: "Stale entry", key: documentCacheKey("123", "en"), age: 90 * time.Second, expectedStatus: CacheStale, expectDocument: true},
+		{name: "Expired entry", key: documentCacheKey("123", "en"), age: 3 * time.Minute, expectedStatus: CacheMiss, expectDocument: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.now = func() time.Time { return now.Add(tt.age) }
+
+			result, status, err := cache.Get(tt.key)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, status)
+			if tt.expectDocument {
+				assert.Equal(t, document, result)
+			} else {
+				assert.Nil(t, result)
+			}
+		})
+	}
+
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestDocumentCacheEviction(t *testing.T) {
+	cache := newDocumentCache(time.Minute, 0, 0, 2)
+
+	cache.Set("a", map[string]interface{}{"id": "a"})
+	cache.Set("b", map[string]interface{}{"id": "b"})
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	_, status, _ := cache.Get("a")
+	assert.Equal(t, CacheHit, status)
+
+	cache.Set("c", map[string]interface{}{"id": "c"})
+
+	assert.Equal(t, 2, cache.Len())
+	_, status, _ = cache.Get("b")
+	assert.Equal(t, CacheMiss, status)
+	_, status, _ = cache.Get("a")
+	assert.Equal(t, CacheHit, status)
+	_, status, _ = cache.Get("c")
+	assert.Equal(t, CacheHit, status)
+}
+
+func TestUpstreamClientCache(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture := func(doc string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "123"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "123", "en.json"), []byte(doc), 0o644))
+	}
+	writeFixture(`{"version": "first"}`)
+	upstream := fakeupstream.New(dir, fakeupstream.Scenario{}, 1)
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	now := time.Now()
+	client := NewUpstreamClient(server.URL, time.Second)
+	client.cache = newDocumentCache(time.Minute, time.Minute, 0, 10)
+	client.cache.now = func() time.Time { return now }
+
+	// First request misses and populates the cache
+	result, meta, err := client.FetchPropertyDocument(context.Background(), "123", []string{"en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, meta.CacheStatus)
+	assert.Equal(t, "first", result["version"])
+
+	// Second request is served from the cache
+	result, meta, err = client.FetchPropertyDocument(context.Background(), "123", []string{"en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheHit, meta.CacheStatus)
+	assert.Equal(t, "first", result["version"])
+	assert.Equal(t, 1, upstream.Requests())
+
+	// Once past the TTL the stale document is served while it is refreshed
+	writeFixture(`{"version": "second"}`)
+	now = now.Add(90 * time.Second)
+	result, meta, err = client.FetchPropertyDocument(context.Background(), "123", []string{"en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheStale, meta.CacheStatus)
+	assert.Equal(t, "first", result["version"])
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		result, meta, err = client.FetchPropertyDocument(context.Background(), "123", []string{"en"})
+		if meta.CacheStatus == CacheHit || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, CacheHit, meta.CacheStatus)
+	assert.Equal(t, "second", result["version"])
+	assert.Equal(t, 2, upstream.Requests())
+}
+
+func TestUpstreamClientCachesMissingLanguage(t *testing.T) {
+	// HA-3213808988 has no French fixture
+	upstream, url := startFakeUpstream(t, fakeupstream.Scenario{})
+
+	now := time.Now()
+	client := NewUpstreamClient(url, time.Second)
+	client.cache = newDocumentCache(time.Minute, 0, 30*time.Second, 10)
+	client.cache.now = func() time.Time { return now }
+
+	// The first request falls back to English and caches both answers
+	_, meta, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr", "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, meta.CacheStatus)
+	assert.Equal(t, "en", meta.Language)
+	assert.Equal(t, 2, upstream.Requests())
+
+	// The fallback is then served from the cache
+	result, meta, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr", "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheHit, meta.CacheStatus)
+	assert.Equal(t, "en", meta.Language)
+	assert.NotNil(t, result["OS"])
+	assert.Equal(t, 2, upstream.Requests())
+
+	// A cached 404 is still an error when there is no other language
+	_, meta, err = client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr"})
+	assert.True(t, isMissingLanguage(nil, err))
+	assert.Equal(t, CacheHit, meta.CacheStatus)
+	assert.Equal(t, 2, upstream.Requests())
+
+	// The missing language is asked for again after its shorter TTL
+	now = now.Add(45 * time.Second)
+	_, meta, err = client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr", "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, meta.CacheStatus)
+	assert.Equal(t, 3, upstream.Requests())
+}
+
+func TestFetchMetaMerge(t *testing.T) {
+	meta := FetchMeta{}
+	meta = meta.Merge(FetchMeta{CacheStatus: CacheHit})
+	assert.Equal(t, CacheHit, meta.CacheStatus)
+
+	meta = meta.Merge(FetchMeta{CacheStatus: CacheMiss, Attempts: 2, Language: "fr"})
+	meta = meta.Merge(FetchMeta{CacheStatus: CacheStale, Language: "en"})
+	meta = meta.Merge(FetchMeta{CacheStatus: CacheMiss, Attempts: 1, Language: "fr"})
+	assert.Equal(t, CacheMiss, meta.CacheStatus)
+	assert.Equal(t, 3, meta.Attempts)
+	assert.Equal(t, "fr, en", meta.Language)
+}
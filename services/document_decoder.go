@@ -0,0 +1,253 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every offending field found while decoding an
+// upstream document.
+type ValidationError struct {
+	Fields []structs.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Path + " " + field.Reason
+	}
+	return "invalid upstream document: " + strings.Join(fields, "; ")
+}
+
+// fieldRule says whether a field must be present and non-null.
+type fieldRule bool
+
+const (
+	required fieldRule = true
+	optional fieldRule = false
+)
+
+// docNode is a JSON object inside an upstream document along with its path,
+// which is used to report errors.
+type docNode struct {
+	path   string
+	fields map[string]interface{}
+}
+
+// documentDecoder reads typed values out of a decoded JSON document. Instead
+// of panicking on a missing or mistyped field it records a FieldError and
+// returns the zero value, so that one pass reports every problem.
+type documentDecoder struct {
+	errs []structs.FieldError
+}
+
+// root wraps a whole document.
+func (d *documentDecoder) root(document map[string]interface{}) docNode {
+	return docNode{fields: document}
+}
+
+// Err returns a *ValidationError if any field failed to decode.
+func (d *documentDecoder) Err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: d.errs}
+}
+
+func (d *documentDecoder) fail(path, format string, args ...interface{}) {
+	d.errs = append(d.errs, structs.FieldError{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// lookup returns the raw value of key and whether it is present and non-null,
+// recording an error when a required field is missing.
+func (d *documentDecoder) lookup(node docNode, key string, rule fieldRule) (interface{}, string, bool) {
+	path := joinPath(node.path, key)
+	value := node.fields[key]
+	if value == nil {
+		if rule == required && node.fields != nil {
+			d.fail(path, "is required")
+		}
+		return nil, path, false
+	}
+	return value, path, true
+}
+
+// Object returns the object stored under key. The returned node is empty,
+// but still usable, when the field is absent or invalid.
+func (d *documentDecoder) Object(node docNode, key string, rule fieldRule) (docNode, bool) {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return docNode{path: path}, false
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		d.fail(path, "must be an object, got %s", jsonType(value))
+		return docNode{path: path}, false
+	}
+	return docNode{path: path, fields: fields}, true
+}
+
+// Objects returns the array of objects stored under key.
+func (d *documentDecoder) Objects(node docNode, key string, rule fieldRule) []docNode {
+	items, path := d.array(node, key, rule)
+	nodes := make([]docNode, 0, len(items))
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			d.fail(itemPath, "must be an object, got %s", jsonType(item))
+			continue
+		}
+		nodes = append(nodes, docNode{path: itemPath, fields: fields})
+	}
+	return nodes
+}
+
+// String returns the string stored under key.
+func (d *documentDecoder) String(node docNode, key string, rule fieldRule) string {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		d.fail(path, "must be a string, got %s", jsonType(value))
+	}
+	return s
+}
+
+// Strings returns the array of strings stored under key.
+func (d *documentDecoder) Strings(node docNode, key string, rule fieldRule) []string {
+	items, path := d.array(node, key, rule)
+	if items == nil {
+		return nil
+	}
+	values := []string{}
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			d.fail(fmt.Sprintf("%s[%d]", path, i), "must be a string, got %s", jsonType(item))
+			continue
+		}
+		values = append(values, s)
+	}
+	return values
+}
+
+// Float returns the number stored under key.
+func (d *documentDecoder) Float(node docNode, key string, rule fieldRule) float64 {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return 0
+	}
+	f, ok := value.(float64)
+	if !ok {
+		d.fail(path, "must be a number, got %s", jsonType(value))
+	}
+	return f
+}
+
+// Int returns the number stored under key, truncating any fraction.
+func (d *documentDecoder) Int(node docNode, key string, rule fieldRule) int {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return 0
+	}
+	f, ok := value.(float64)
+	if !ok {
+		d.fail(path, "must be a number, got %s", jsonType(value))
+		return 0
+	}
+	return int(f)
+}
+
+// Bool returns the boolean stored under key.
+func (d *documentDecoder) Bool(node docNode, key string, rule fieldRule) bool {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		d.fail(path, "must be a boolean, got %s", jsonType(value))
+	}
+	return b
+}
+
+// StringMap returns the object of strings stored under key.
+func (d *documentDecoder) StringMap(node docNode, key string, rule fieldRule) map[string]string {
+	object, ok := d.Object(node, key, rule)
+	if !ok {
+		return nil
+	}
+	values := make(map[string]string, len(object.fields))
+	for _, k := range sortedKeys(object.fields) {
+		values[k] = d.String(object, k, required)
+	}
+	return values
+}
+
+// FloatMap returns the object of numbers stored under key.
+func (d *documentDecoder) FloatMap(node docNode, key string, rule fieldRule) map[string]float64 {
+	object, ok := d.Object(node, key, rule)
+	if !ok {
+		return nil
+	}
+	values := make(map[string]float64, len(object.fields))
+	for _, k := range sortedKeys(object.fields) {
+		values[k] = d.Float(object, k, required)
+	}
+	return values
+}
+
+func (d *documentDecoder) array(node docNode, key string, rule fieldRule) ([]interface{}, string) {
+	value, path, ok := d.lookup(node, key, rule)
+	if !ok {
+		return nil, path
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		d.fail(path, "must be an array, got %s", jsonType(value))
+		return nil, path
+	}
+	return items, path
+}
+
+// sortedKeys returns the keys of fields in order, so errors are reported in
+// a stable order.
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// jsonType names the JSON type of a decoded value for error messages.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BalancingStrategy decides which upstream endpoint gets the next request.
+type BalancingStrategy string
+
+const (
+	BalanceRoundRobin   BalancingStrategy = "round_robin"
+	BalanceLeastLatency BalancingStrategy = "least_latency"
+)
+
+// healthSmoothing is the weight of the newest sample in the moving averages
+// of error rate and latency.
+const healthSmoothing = 0.2
+
+// upstreamEndpoint is one replica of the external API together with its
+// passively observed health.
+type upstreamEndpoint struct {
+	baseURL string
+	weight  int
+
+	errorRate float64
+	samples   int
+	// latency only averages successful responses, measured of them; a
+	// failure that returns at once says nothing about the replica's speed.
+	latency       time.Duration
+	measured      int
+	tried         bool
+	ejectedUntil  time.Time
+	currentWeight int
+}
+
+// latencyRank orders endpoints for least_latency: untried ones first so that
+// they get measured, then measured ones, then those that have only failed.
+func (e *upstreamEndpoint) latencyRank() int {
+	switch {
+	case !e.tried:
+		return 0
+	case e.measured > 0:
+		return 1
+	}
+	return 2
+}
+
+// readmit gives an endpoint whose ejection has expired a fresh error rate,
+// so that it is not ejected again by its first failure.
+func (e *upstreamEndpoint) readmit(now time.Time) {
+	if e.ejectedUntil.IsZero() || now.Before(e.ejectedUntil) {
+		return
+	}
+	e.ejectedUntil = time.Time{}
+	e.errorRate = 0
+	e.samples = 0
+}
+
+// endpointPool spreads requests over several upstream replicas. Every
+// response feeds moving averages of the endpoint's error rate and latency;
+// an endpoint whose error rate reaches maxErrorRate is ejected for ejectFor
+// and only used when no healthy endpoint is left.
+type endpointPool struct {
+	mu sync.Mutex
+
+	endpoints    []*upstreamEndpoint
+	strategy     BalancingStrategy
+	maxErrorRate float64
+	minSamples   int
+	ejectFor     time.Duration
+	now          func() time.Time
+}
+
+func newEndpointPool(baseURLs []string, weights []int, strategy BalancingStrategy, maxErrorRate float64, minSamples int, ejectFor time.Duration) *endpointPool {
+	pool := &endpointPool{
+		strategy:     strategy,
+		maxErrorRate: maxErrorRate,
+		minSamples:   minSamples,
+		ejectFor:     ejectFor,
+		now:          time.Now,
+	}
+	for i, baseURL := range baseURLs {
+		weight := 1
+		if i < len(weights) && weights[i] > 0 {
+			weight = weights[i]
+		}
+		pool.endpoints = append(pool.endpoints, &upstreamEndpoint{baseURL: baseURL, weight: weight})
+	}
+	return pool
+}
+
+// order returns the endpoints in the order one request should try them: the
+// endpoint picked by the balancing strategy first, then the other healthy
+// endpoints, then the ejected ones as a last resort.
+func (p *endpointPool) order() []*upstreamEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var healthy, ejected []*upstreamEndpoint
+	for _, endpoint := range p.endpoints {
+		endpoint.readmit(now)
+		if now.Before(endpoint.ejectedUntil) {
+			ejected = append(ejected, endpoint)
+		} else {
+			healthy = append(healthy, endpoint)
+		}
+	}
+
+	switch p.strategy {
+	case BalanceLeastLatency:
+		sort.SliceStable(healthy, func(i, j int) bool {
+			if ri, rj := healthy[i].latencyRank(), healthy[j].latencyRank(); ri != rj {
+				return ri < rj
+			}
+			return healthy[i].latency < healthy[j].latency
+		})
+	default:
+		if picked := pickWeighted(healthy); picked > 0 {
+			healthy[0], healthy[picked] = healthy[picked], healthy[0]
+		}
+	}
+	sort.SliceStable(ejected, func(i, j int) bool {
+		return ejected[i].ejectedUntil.Before(ejected[j].ejectedUntil)
+	})
+
+	return append(healthy, ejected...)
+}
+
+// pickWeighted runs one round of smooth weighted round-robin over endpoints
+// and returns the index of the chosen one.
+func pickWeighted(endpoints []*upstreamEndpoint) int {
+	picked, total := -1, 0
+	for i, endpoint := range endpoints {
+		endpoint.currentWeight += endpoint.weight
+		total += endpoint.weight
+		if picked < 0 || endpoint.currentWeight > endpoints[picked].currentWeight {
+			picked = i
+		}
+	}
+	if picked >= 0 {
+		endpoints[picked].currentWeight -= total
+	}
+	return picked
+}
+
+// record feeds the outcome of one request into the endpoint's health.
+func (p *endpointPool) record(endpoint *upstreamEndpoint, latency time.Duration, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	endpoint.readmit(p.now())
+	endpoint.tried = true
+
+	sample := 0.0
+	if failed {
+		sample = 1
+	}
+	if endpoint.samples == 0 {
+		endpoint.errorRate = sample
+	} else {
+		endpoint.errorRate += healthSmoothing * (sample - endpoint.errorRate)
+	}
+	endpoint.samples++
+
+	if !failed {
+		if endpoint.measured == 0 {
+			endpoint.latency = latency
+		} else {
+			endpoint.latency += time.Duration(healthSmoothing * float64(latency-endpoint.latency))
+		}
+		endpoint.measured++
+	}
+
+	if failed && endpoint.samples >= p.minSamples && endpoint.errorRate >= p.maxErrorRate {
+		endpoint.ejectedUntil = p.now().Add(p.ejectFor)
+		log.Printf("ejecting upstream endpoint %s for %s, error rate %.2f", endpoint.baseURL, p.ejectFor, endpoint.errorRate)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func endpointURLs(endpoints []*upstreamEndpoint) []string {
+	urls := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		urls[i] = endpoint.baseURL
+	}
+	return urls
+}
+
+func TestEndpointPoolWeightedRoundRobin(t *testing.T) {
+	pool := newEndpointPool([]string{"a", "b"}, []int{3, 1}, BalanceRoundRobin, 0.5, 1, time.Minute)
+
+	picks := map[string]int{}
+	var sequence []string
+	for i := 0; i < 8; i++ {
+		first := pool.order()[0].baseURL
+		picks[first]++
+		sequence = append(sequence, first)
+	}
+
+	assert.Equal(t, map[string]int{"a": 6, "b": 2}, picks)
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, sequence)
+}
+
+func TestEndpointPoolLeastLatency(t *testing.T) {
+	pool := newEndpointPool([]string{"a", "b", "c"}, nil, BalanceLeastLatency, 0.5, 1, time.Minute)
+	endpoints := pool.order()
+	pool.record(endpoints[0], 300*time.Millisecond, false)
+	pool.record(endpoints[1], 100*time.Millisecond, false)
+	pool.record(endpoints[2], 200*time.Millisecond, false)
+
+	assert.Equal(t, []string{"b", "c", "a"}, endpointURLs(pool.order()))
+}
+
+func TestEndpointPoolEjection(t *testing.T) {
+	now := time.Now()
+	pool := newEndpointPool([]string{"a", "b"}, nil, BalanceLeastLatency, 0.4, 3, time.Minute)
+	pool.now = func() time.Time { return now }
+	a, b := pool.endpoints[0], pool.endpoints[1]
+	pool.record(b, time.Second, false)
+	pool.record(a, time.Millisecond, false)
+
+	pool.record(a, time.Millisecond, true)
+	pool.record(a, time.Millisecond, true)
+	assert.Equal(t, []string{"a", "b"}, endpointURLs(pool.order()))
+
+	// The third failure takes the error rate over the limit and ejects the
+	// endpoint
+	pool.record(a, time.Millisecond, true)
+	assert.Equal(t, []string{"b", "a"}, endpointURLs(pool.order()))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{"a", "b"}, endpointURLs(pool.order()))
+
+	// Back from ejection its error rate starts afresh, so one failure does
+	// not eject it again but minSamples failures do
+	pool.record(a, time.Millisecond, true)
+	assert.Equal(t, []string{"a", "b"}, endpointURLs(pool.order()))
+	pool.record(a, time.Millisecond, true)
+	pool.record(a, time.Millisecond, true)
+	assert.Equal(t, []string{"b", "a"}, endpointURLs(pool.order()))
+}
+
+func TestEndpointPoolLeastLatencyFastFailure(t *testing.T) {
+	pool := newEndpointPool([]string{"a", "b", "c"}, nil, BalanceLeastLatency, 0.5, 100, time.Minute)
+	a, b := pool.endpoints[0], pool.endpoints[1]
+	pool.record(a, 200*time.Millisecond, false)
+	pool.record(b, 300*time.Millisecond, false)
+
+	// c refuses connections at once: it failed, so it is ordered last
+	assert.Equal(t, []string{"c", "a", "b"}, endpointURLs(pool.order()))
+	pool.record(pool.endpoints[2], 0, true)
+	assert.Equal(t, []string{"a", "b", "c"}, endpointURLs(pool.order()))
+
+	// b's fast failures leave its latency as measured on success
+	for i := 0; i < 5; i++ {
+		pool.record(b, 0, true)
+	}
+	assert.Equal(t, 300*time.Millisecond, b.latency)
+	assert.Equal(t, []string{"a", "b", "c"}, endpointURLs(pool.order()))
+}
+
+func TestUpstreamClientFailover(t *testing.T) {
+	down, downURL := startFakeUpstream(t, fakeupstream.Scenario{ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable})
+	up, upURL := startFakeUpstream(t, fakeupstream.Scenario{})
+
+	web.AppConfig.Set("externalAPIBaseURLs", downURL+";"+upURL)
+	web.AppConfig.Set("upstreamEndpointMinRequests", "2")
+	web.AppConfig.Set("upstreamRetryMaxAttempts", "1")
+	defer web.AppConfig.Set("externalAPIBaseURLs", "")
+	defer web.AppConfig.Set("upstreamEndpointMinRequests", "5")
+	defer web.AppConfig.Set("upstreamRetryMaxAttempts", "3")
+
+	client, err := DefaultUpstreamClient()
+	assert.NoError(t, err)
+	assert.Equal(t, downURL, client.BaseURL)
+
+	for i := 0; i < 4; i++ {
+		originalData, _, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+		assert.NoError(t, err)
+		assert.Contains(t, originalData, "S3")
+	}
+
+	// Round-robin sends every other request to the failing replica until it
+	// is ejected after two failures
+	assert.Equal(t, 2, down.Requests())
+	assert.Equal(t, 4, up.Requests())
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCode is a stable, machine-readable identifier of why a service call failed.
+type ErrorCode string
+
+const (
+	ErrorNotFound            ErrorCode = "property_not_found"
+	ErrorUpstreamUnavailable ErrorCode = "upstream_unavailable"
+	ErrorBadUpstreamPayload  ErrorCode = "bad_upstream_payload"
+	ErrorTimeout             ErrorCode = "upstream_timeout"
+	ErrorCanceled            ErrorCode = "request_canceled"
+	ErrorInternal            ErrorCode = "internal_error"
+)
+
+// ServiceError is the typed error returned by the Fetch* functions.
+type ServiceError struct {
+	Code       ErrorCode
+	PropertyID string
+	// RetryAfter tells clients how long to wait before retrying, if known.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ServiceError) Error() string {
+	if e.PropertyID == "" {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("%s for property %s: %v", e.Code, e.PropertyID, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code of err, classifying plain context errors and
+// treating anything unrecognised as internal.
+func ErrorCodeOf(err error) ErrorCode {
+	var serviceErr *ServiceError
+	switch {
+	case errors.As(err, &serviceErr):
+		return serviceErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	}
+	return ErrorInternal
+}
+
+// RetryAfterOf returns the Retry-After hint carried by err, or 0.
+func RetryAfterOf(err error) time.Duration {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.RetryAfter
+	}
+	return 0
+}
+
+// newServiceError classifies an upstream client error.
+func newServiceError(propertyId string, err error) *ServiceError {
+	serviceErr := &ServiceError{Code: ErrorInternal, PropertyID: propertyId, Err: err}
+
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		serviceErr.Code = ErrorCodeOf(err)
+		return serviceErr
+	}
+
+	serviceErr.RetryAfter = upstreamErr.RetryAfter
+	switch upstreamErr.Kind {
+	case UpstreamErrorStatus:
+		switch {
+		case upstreamErr.StatusCode == http.StatusNotFound,
+			upstreamErr.StatusCode == http.StatusGone:
+			serviceErr.Code = ErrorNotFound
+		case upstreamErr.Temporary():
+			serviceErr.Code = ErrorUpstreamUnavailable
+		default:
+			serviceErr.Code = ErrorBadUpstreamPayload
+		}
+	case UpstreamErrorTransport, UpstreamErrorCircuitOpen:
+		serviceErr.Code = ErrorUpstreamUnavailable
+	case UpstreamErrorTimeout:
+		serviceErr.Code = ErrorTimeout
+	case UpstreamErrorDecode:
+		serviceErr.Code = ErrorBadUpstreamPayload
+	case UpstreamErrorCanceled:
+		serviceErr.Code = ErrorCanceled
+	}
+	return serviceErr
+}
+
+// newPayloadError reports an upstream document that could not be transformed.
+func newPayloadError(propertyId string, err error) *ServiceError {
+	return &ServiceError{Code: ErrorBadUpstreamPayload, PropertyID: propertyId, Err: err}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServiceError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode ErrorCode
+	}{
+		{
+			name:         "Upstream 404",
+			err:          &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusNotFound},
+			expectedCode: ErrorNotFound,
+		},
+		{
+			name:         "Upstream 400",
+			err:          &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusBadRequest},
+			expectedCode: ErrorBadUpstreamPayload,
+		},
+		{
+			name:         "Upstream 503",
+			err:          &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusServiceUnavailable},
+			expectedCode: ErrorUpstreamUnavailable,
+		},
+		{
+			name:         "Upstream 401",
+			err:          &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusUnauthorized},
+			expectedCode: ErrorBadUpstreamPayload,
+		},
+		{
+			name:         "Transport failure",
+			err:          &UpstreamError{Kind: UpstreamErrorTransport},
+			expectedCode: ErrorUpstreamUnavailable,
+		},
+		{
+			name:         "Circuit open",
+			err:          &UpstreamError{Kind: UpstreamErrorCircuitOpen},
+			expectedCode: ErrorUpstreamUnavailable,
+		},
+		{
+			name:         "Timeout",
+			err:          &UpstreamError{Kind: UpstreamErrorTimeout},
+			expectedCode: ErrorTimeout,
+		},
+		{
+			name:         "Malformed JSON",
+			err:          &UpstreamError{Kind: UpstreamErrorDecode},
+			expectedCode: ErrorBadUpstreamPayload,
+		},
+		{
+			name:         "Missing configuration",
+			err:          &UpstreamError{Kind: UpstreamErrorConfig},
+			expectedCode: ErrorInternal,
+		},
+		{
+			name:         "Caller deadline",
+			err:          fmt.Errorf("fetch: %w", context.DeadlineExceeded),
+			expectedCode: ErrorTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newServiceError("123", tt.err)
+
+			assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+			assert.True(t, errors.Is(err, tt.err))
+		})
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	assert.Equal(t, ErrorTimeout, ErrorCodeOf(context.DeadlineExceeded))
+	assert.Equal(t, ErrorCanceled, ErrorCodeOf(context.Canceled))
+	assert.Equal(t, ErrorInternal, ErrorCodeOf(errors.New("boom")))
+	assert.Equal(t, ErrorNotFound, ErrorCodeOf(fmt.Errorf("wrapped: %w", &ServiceError{Code: ErrorNotFound})))
+}
+
+func TestRetryAfterOf(t *testing.T) {
+	err := newServiceError("123", &UpstreamError{Kind: UpstreamErrorCircuitOpen, RetryAfter: 3 * time.Second})
+
+	assert.Equal(t, 3*time.Second, RetryAfterOf(err))
+	assert.Equal(t, time.Duration(0), RetryAfterOf(errors.New("boom")))
+}
+
+func TestFetchErrorCodes(t *testing.T) {
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{
+		Properties: map[string]fakeupstream.Scenario{"TEST-S3": {ErrorRate: 1, ErrorStatus: http.StatusBadGateway}},
+	})
+	web.AppConfig.Set("externalAPIBaseURL", url)
+
+	tests := []struct {
+		propertyID   string
+		expectedCode ErrorCode
+	}{
+		{propertyID: "HA-404", expectedCode: ErrorNotFound},
+		{propertyID: "TEST-EMPTY", expectedCode: ErrorNotFound},
+		{propertyID: "TEST-BAD-S3", expectedCode: ErrorBadUpstreamPayload},
+		{propertyID: "TEST-S3", expectedCode: ErrorUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.propertyID, func(t *testing.T) {
+			_, _, err := FetchPropertyDetails(context.Background(), tt.propertyID, FetchOptions{})
+
+			assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+		})
+	}
+}
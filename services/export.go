@@ -0,0 +1,181 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// exportListSeparator joins the values of lists and maps in one cell.
+const exportListSeparator = "; "
+
+// ExportColumn is one column of a CSV or XLSX export: the dotted path of the
+// value and the name shown in the header row.
+type ExportColumn struct {
+	Path   string
+	Header string
+}
+
+// exportItemColumns describe the bulk item rather than the property details.
+// ID is the requested ID, so that failed rows can be told apart too.
+var exportItemColumns = []string{"ID", "Status", "Error"}
+
+// ExportColumnPaths lists every column an export can have, in default order:
+// the item columns followed by the property details flattened leaf by leaf,
+// e.g. GeoInfo.City or Property.Counts.Bedroom.
+func ExportColumnPaths() []string {
+	paths := append([]string{}, exportItemColumns...)
+	exportPaths(reflect.TypeOf(structs.PropertyDetailsResponse{}), "", func(path string) {
+		if path != "ID" {
+			paths = append(paths, path)
+		}
+	})
+	return paths
+}
+
+// ParseExportColumns picks the columns of an export from specs written as
+// "path" or "path:Header". A path naming a group, such as Partner, selects
+// every column below it. No specs selects every column.
+func ParseExportColumns(specs []string) ([]ExportColumn, error) {
+	available := ExportColumnPaths()
+
+	var columns []ExportColumn
+	var unknown []string
+	for _, spec := range specs {
+		path, header, renamed := strings.Cut(strings.TrimSpace(spec), ":")
+		path, header = strings.TrimSpace(path), strings.TrimSpace(header)
+		if path == "" {
+			continue
+		}
+
+		var matched []string
+		for _, candidate := range available {
+			if candidate == path || strings.HasPrefix(candidate, path+".") {
+				matched = append(matched, candidate)
+			}
+		}
+		if len(matched) == 0 {
+			unknown = append(unknown, path)
+			continue
+		}
+		for _, match := range matched {
+			column := ExportColumn{Path: match, Header: match}
+			if renamed && header != "" {
+				// A renamed group keeps the rest of each path after the new name.
+				column.Header = header + strings.TrimPrefix(match, path)
+			}
+			columns = append(columns, column)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownFieldsError{Fields: unknown}
+	}
+
+	if len(columns) == 0 {
+		for _, path := range available {
+			columns = append(columns, ExportColumn{Path: path, Header: path})
+		}
+	}
+	return columns, nil
+}
+
+// ExportRow flattens a bulk item into the values of columns. Values are
+// strings, numbers or booleans; lists and maps are joined into one string.
+// Items without property details, such as failed ones, only fill the item
+// columns.
+func ExportRow(item structs.BulkItem, columns []ExportColumn) []interface{} {
+	values := map[string]interface{}{"ID": item.ID, "Status": item.Status, "Error": ""}
+	if item.Error != nil {
+		values["Error"] = item.Error.Message
+	}
+	if details, ok := item.Data.(structs.PropertyDetailsResponse); ok {
+		flattenValue(reflect.ValueOf(details), "", values)
+		values["ID"] = item.ID
+	}
+
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if value, ok := values[column.Path]; ok {
+			row[i] = value
+		} else {
+			row[i] = ""
+		}
+	}
+	return row
+}
+
+// exportPaths visits the leaf paths of the JSON form of t. Lists of objects
+// are flattened through the list, like GeoInfo.Categories.Name.
+func exportPaths(t reflect.Type, path string, visit func(path string)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		visit(path)
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			exportPaths(t.Field(i).Type, joinPath(path, name), visit)
+		}
+	}
+}
+
+// flattenValue stores the leaves of value in values under the paths that
+// exportPaths lists.
+func flattenValue(value reflect.Value, path string, values map[string]interface{}) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			flattenValue(value.Elem(), path, values)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if name := jsonName(value.Type().Field(i)); name != "" {
+				flattenValue(value.Field(i), joinPath(path, name), values)
+			}
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			// Join each leaf across the elements of the list
+			parts := map[string][]string{}
+			for i := 0; i < value.Len(); i++ {
+				element := map[string]interface{}{}
+				flattenValue(value.Index(i), path, element)
+				for key, leaf := range element {
+					parts[key] = append(parts[key], fmt.Sprint(leaf))
+				}
+			}
+			for key, leaves := range parts {
+				values[key] = strings.Join(leaves, exportListSeparator)
+			}
+			return
+		}
+		parts := make([]string, value.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(value.Index(i).Interface())
+		}
+		values[path] = strings.Join(parts, exportListSeparator)
+	case reflect.Map:
+		keys := make([]string, 0, value.Len())
+		entries := map[string]string{}
+		for _, key := range value.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			keys = append(keys, name)
+			entries[name] = fmt.Sprint(value.MapIndex(key).Interface())
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key + ": " + entries[key]
+		}
+		values[path] = strings.Join(parts, exportListSeparator)
+	default:
+		values[path] = value.Interface()
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportColumnPaths(t *testing.T) {
+	paths := ExportColumnPaths()
+
+	assert.Equal(t, []string{"ID", "Status", "Error", "Feed", "Published", "GeoInfo.Categories.Name"}, paths[:6])
+	assert.Contains(t, paths, "GeoInfo.City")
+	assert.Contains(t, paths, "Property.Counts.Bedroom")
+	assert.Contains(t, paths, "Property.Image.Images")
+	assert.Contains(t, paths, "Property.Amenities")
+	assert.Contains(t, paths, "Partner.OwnerID")
+	assert.NotContains(t, paths, "Property.Counts")
+}
+
+func TestParseExportColumns(t *testing.T) {
+	tests := []struct {
+		name          string
+		specs         []string
+		expected      []ExportColumn
+		expectedError []string
+	}{
+		{
+			name:  "paths and headers",
+			specs: []string{"ID: Property ID", " GeoInfo.City ", "Partner.OwnerID:Owner", ""},
+			expected: []ExportColumn{
+				{Path: "ID", Header: "Property ID"},
+				{Path: "GeoInfo.City", Header: "GeoInfo.City"},
+				{Path: "Partner.OwnerID", Header: "Owner"},
+			},
+		},
+		{
+			name:  "group",
+			specs: []string{"Property.Counts:Counts"},
+			expected: []ExportColumn{
+				{Path: "Property.Counts.Bedroom", Header: "Counts.Bedroom"},
+				{Path: "Property.Counts.Bathroom", Header: "Counts.Bathroom"},
+				{Path: "Property.Counts.Reviews", Header: "Counts.Reviews"},
+				{Path: "Property.Counts.Occupancy", Header: "Counts.Occupancy"},
+			},
+		},
+		{
+			name:          "unknown columns",
+			specs:         []string{"ID", "Property.Count", "Partner.OwnerID.Name"},
+			expectedError: []string{"Property.Count", "Partner.OwnerID.Name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := ParseExportColumns(tt.specs)
+
+			if tt.expectedError != nil {
+				var unknownErr *UnknownFieldsError
+				assert.True(t, errors.As(err, &unknownErr))
+				assert.Equal(t, tt.expectedError, unknownErr.Fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, columns)
+		})
+	}
+
+	all, err := ParseExportColumns(nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, len(ExportColumnPaths()))
+}
+
+func TestExportRow(t *testing.T) {
+	var details structs.PropertyDetailsResponse
+	assert.NoError(t, transformData(getMockValidResponse("2025-01-09T06:11:56Z"), &details))
+	details.GeoInfo.Categories = append(details.GeoInfo.Categories, details.GeoInfo.Categories[0])
+	details.GeoInfo.Categories[1].Name = "Category2"
+
+	columns, err := ParseExportColumns([]string{
+		"ID", "Status", "Error", "Feed", "Published", "GeoInfo.City", "GeoInfo.Categories.Name",
+		"Property.Counts.Bedroom", "Property.RoomSize", "Property.Amenities", "Property.Image.Images", "Partner.Archived",
+	})
+	assert.NoError(t, err)
+
+	row := ExportRow(structs.BulkItem{ID: "HA-1", Status: structs.BulkItemOK, Data: details}, columns)
+	assert.Equal(t, []interface{}{
+		"HA-1", "ok", "", 1, true, "Test City", "Category1; Category2",
+		2, 50.5, "pool: Available; wifi: Available", "img1.jpg; img2.jpg", "archived1; archived2",
+	}, row)
+
+	failed := ExportRow(structs.BulkItem{
+		ID:     "HA-2",
+		Status: structs.BulkItemNotFound,
+		Error:  &structs.ErrorResponse{Code: "property_not_found", Message: "Property not found"},
+	}, columns)
+	assert.Equal(t, []interface{}{"HA-2", "not_found", "Property not found", "", "", "", "", "", "", "", "", ""}, failed)
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"beego-api-service/internal/fakeupstream"
+)
+
+// startFakeUpstream serves the shared fakeupstream fixtures with scenario
+// until the test ends, returning the server and its URL.
+func startFakeUpstream(t *testing.T, scenario fakeupstream.Scenario) (*fakeupstream.Server, string) {
+	upstream := fakeupstream.New(fakeupstream.FixturesDir(), scenario, 1)
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	return upstream, server.URL
+}
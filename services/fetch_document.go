@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// fetchDocument loads the upstream document for propertyId in the language
+// chain of opts. Failures are returned as *ServiceError.
+func fetchDocument(ctx context.Context, propertyId string, opts FetchOptions) (map[string]interface{}, FetchMeta, error) {
+	client, err := DefaultUpstreamClient()
+	if err != nil {
+		log.Printf("failed to create upstream client: %v", err)
+		return nil, FetchMeta{}, newServiceError(propertyId, err)
+	}
+
+	originalData, meta, err := client.FetchPropertyDocument(ctx, propertyId, languageChain(opts.Language))
+	if err != nil {
+		log.Printf("failed to fetch property document: %v", err)
+		return nil, meta, newServiceError(propertyId, err)
+	}
+
+	if isMissingLanguage(originalData, nil) {
+		return nil, meta, &ServiceError{Code: ErrorNotFound, PropertyID: propertyId, Err: errors.New("upstream returned an empty document")}
+	}
+
+	return originalData, meta, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import "strings"
+
+// FetchMeta describes how the upstream data behind a response was obtained.
+type FetchMeta struct {
+	CacheStatus CacheStatus
+	// Attempts is the number of upstream requests made, 0 for cache hits.
+	Attempts int
+	// Language is the language actually served, after any fallback.
+	Language string
+}
+
+// Merge combines the metadata of several upstream fetches, e.g. for bulk
+// requests, keeping the least favourable cache status, the total number of
+// upstream attempts and every language served.
+func (m FetchMeta) Merge(other FetchMeta) FetchMeta {
+	if cacheStatusRank[other.CacheStatus] > cacheStatusRank[m.CacheStatus] {
+		m.CacheStatus = other.CacheStatus
+	}
+	m.Attempts += other.Attempts
+	if other.Language != "" && !containsLanguage(m.Language, other.Language) {
+		if m.Language == "" {
+			m.Language = other.Language
+		} else {
+			m.Language += ", " + other.Language
+		}
+	}
+	return m
+}
+
+func containsLanguage(languages, languageCode string) bool {
+	for _, lang := range strings.Split(languages, ", ") {
+		if lang == languageCode {
+			return true
+		}
+	}
+	return false
+}
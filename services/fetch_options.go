@@ -0,0 +1,9 @@
+package services
+
+// FetchOptions carries the choices a client made for one request.
+type FetchOptions struct {
+	// Language is the requested language; empty means defaultLanguage.
+	Language string
+	// Source selects the upstream block; empty means the endpoint default.
+	Source Source
+}
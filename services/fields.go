@@ -0,0 +1,171 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldSet is a tree of selected response fields. An empty FieldSet selects
+// everything below it.
+type FieldSet map[string]FieldSet
+
+// UnknownFieldsError lists selected field paths that the response does not
+// have.
+type UnknownFieldsError struct {
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// ParseFields builds a FieldSet from dotted field paths such as
+// Property.Price, checking every path against the JSON shape of model.
+// Fields of list elements are selected through the list, e.g.
+// GeoInfo.Categories.Name. Blank paths are ignored.
+func ParseFields(paths []string, model interface{}) (FieldSet, error) {
+	fields := FieldSet{}
+	var unknown []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !hasField(reflect.TypeOf(model), strings.Split(path, ".")) {
+			unknown = append(unknown, path)
+			continue
+		}
+		fields.add(strings.Split(path, "."))
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownFieldsError{Fields: unknown}
+	}
+	return fields, nil
+}
+
+func (f FieldSet) add(parts []string) {
+	child, ok := f[parts[0]]
+	if ok && len(child) == 0 {
+		// The whole field is already selected.
+		return
+	}
+	if len(parts) == 1 {
+		f[parts[0]] = FieldSet{}
+		return
+	}
+	if !ok {
+		child = FieldSet{}
+		f[parts[0]] = child
+	}
+	child.add(parts[1:])
+}
+
+// Selects reports whether the field at path, such as Property.Price, is
+// selected, on its own or as part of a selected group. Everything is selected
+// by an empty FieldSet.
+func (f FieldSet) Selects(path string) bool {
+	node := f
+	for _, part := range strings.Split(path, ".") {
+		if len(node) == 0 {
+			return true
+		}
+		child, ok := node[part]
+		if !ok {
+			return false
+		}
+		node = child
+	}
+	return true
+}
+
+// SelectProvenance keeps the provenance of the selected fields only.
+func (f FieldSet) SelectProvenance(provenance structs.Provenance) structs.Provenance {
+	if len(f) == 0 || provenance == nil {
+		return provenance
+	}
+	selected := structs.Provenance{}
+	for path, source := range provenance {
+		if f.Selects(path) {
+			selected[path] = source
+		}
+	}
+	return selected
+}
+
+// Project returns the JSON form of value reduced to the selected fields. With
+// no fields selected value is returned unchanged.
+func (f FieldSet) Project(value interface{}) (interface{}, error) {
+	if len(f) == 0 {
+		return value, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return f.project(generic), nil
+}
+
+func (f FieldSet) project(value interface{}) interface{} {
+	if len(f) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(f))
+		for name, child := range f {
+			if fieldValue, ok := v[name]; ok {
+				projected[name] = child.project(fieldValue)
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, element := range v {
+			projected[i] = f.project(element)
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+// hasField reports whether the JSON form of t has the dotted path.
+func hasField(t reflect.Type, parts []string) bool {
+	if len(parts) == 0 {
+		return true
+	}
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" && name == parts[0] {
+			return hasField(t.Field(i).Type, parts[1:])
+		}
+	}
+	return false
+}
+
+// jsonName is the key encoding/json uses for field, or "" if it is skipped.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		paths         []string
+		expected      FieldSet
+		expectedError []string
+	}{
+		{
+			name:     "no fields",
+			paths:    nil,
+			expected: FieldSet{},
+		},
+		{
+			name:  "nested paths",
+			paths: []string{"ID", " Property.Price ", "Property.Counts.Bedroom", ""},
+			expected: FieldSet{
+				"ID":       FieldSet{},
+				"Property": FieldSet{"Price": FieldSet{}, "Counts": FieldSet{"Bedroom": FieldSet{}}},
+			},
+		},
+		{
+			name:     "whole field wins over its children",
+			paths:    []string{"Partner.ID", "Partner", "Partner.URL"},
+			expected: FieldSet{"Partner": FieldSet{}},
+		},
+		{
+			name:     "fields of list elements and pointers",
+			paths:    []string{"GeoInfo.Categories.Name", "Property.Image.Count"},
+			expected: FieldSet{"GeoInfo": FieldSet{"Categories": FieldSet{"Name": FieldSet{}}}, "Property": FieldSet{"Image": FieldSet{"Count": FieldSet{}}}},
+		},
+		{
+			name:          "unknown paths",
+			paths:         []string{"ID", "Property.Nope", "Price", "Property.Amenities.wifi"},
+			expectedError: []string{"Property.Nope", "Price", "Property.Amenities.wifi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseFields(tt.paths, structs.PropertyDetailsResponse{})
+
+			if tt.expectedError != nil {
+				var unknownErr *UnknownFieldsError
+				assert.True(t, errors.As(err, &unknownErr))
+				assert.Equal(t, tt.expectedError, unknownErr.Fields)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestFieldSetProject(t *testing.T) {
+	var details structs.PropertyDetailsResponse
+	assert.NoError(t, transformData(getMockValidResponse("2025-01-09T06:11:56Z"), &details))
+
+	fields, err := ParseFields([]string{"ID", "Property.Price", "Property.ReviewScores", "GeoInfo.Categories.Name"}, details)
+	assert.NoError(t, err)
+
+	projected, err := fields.Project(details)
+	assert.NoError(t, err)
+
+	encoded, err := json.Marshal(projected)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"ID": "TEST123",
+		"Property": {"Price": 100, "ReviewScores": {"Cleanliness": 4.5, "Location": 4.2}},
+		"GeoInfo": {"Categories": [{"Name": "Category1"}]}
+	}`, string(encoded))
+
+	unchanged, err := FieldSet{}.Project(details)
+	assert.NoError(t, err)
+	assert.Equal(t, details, unchanged)
+}
+
+func TestFieldSetSelects(t *testing.T) {
+	fields, err := ParseFields([]string{"ID", "Property.Counts", "GeoInfo.Categories.Name"}, structs.PropertyDetailsResponse{})
+	assert.NoError(t, err)
+
+	assert.True(t, fields.Selects("ID"))
+	assert.True(t, fields.Selects("Property.Counts.Bedroom"))
+	assert.True(t, fields.Selects("GeoInfo.Categories.Name"))
+	assert.False(t, fields.Selects("Property.Price"))
+	assert.False(t, fields.Selects("Partner.ID"))
+	assert.True(t, FieldSet{}.Selects("Partner.ID"))
+
+	provenance := structs.Provenance{
+		"ID":                      {Source: "S3", Key: "ID"},
+		"Property.Counts.Bedroom": {Source: "OS", Key: "bedrooms"},
+		"Property.Price":          {Source: "OS", Key: "usd_price"},
+	}
+	assert.Equal(t, structs.Provenance{
+		"ID":                      {Source: "S3", Key: "ID"},
+		"Property.Counts.Bedroom": {Source: "OS", Key: "bedrooms"},
+	}, fields.SelectProvenance(provenance))
+	assert.Equal(t, provenance, FieldSet{}.SelectProvenance(provenance))
+}
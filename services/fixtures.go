@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UpstreamMode selects whether the upstream client talks to the external API,
+// records its answers, or replays recorded answers.
+type UpstreamMode string
+
+const (
+	UpstreamModePassthrough UpstreamMode = "passthrough"
+	UpstreamModeRecord      UpstreamMode = "record"
+	UpstreamModeReplay      UpstreamMode = "replay"
+)
+
+// parseUpstreamMode falls back to passthrough for unknown values.
+func parseUpstreamMode(value string) UpstreamMode {
+	switch mode := UpstreamMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case UpstreamModeRecord, UpstreamModeReplay:
+		return mode
+	case "", UpstreamModePassthrough:
+	default:
+		log.Printf("unknown upstream mode %q, using passthrough", value)
+	}
+	return UpstreamModePassthrough
+}
+
+// fixtureStore keeps upstream documents on disk as <dir>/<propertyId>/<languageCode>.json.
+type fixtureStore struct {
+	dir string
+}
+
+// path returns the fixture file for a document. IDs and languages are
+// escaped so that they cannot point outside dir.
+func (s *fixtureStore) path(propertyId, languageCode string) (string, error) {
+	id, lang := url.PathEscape(propertyId), url.PathEscape(languageCode)
+	for _, part := range []string{id, lang} {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid fixture key %q/%q", propertyId, languageCode)
+		}
+	}
+	return filepath.Join(s.dir, id, lang+".json"), nil
+}
+
+// Load reads a recorded document. A missing fixture is reported like an
+// upstream 404 so that language fallbacks and not-found handling still apply.
+func (s *fixtureStore) Load(propertyId, languageCode string) (map[string]interface{}, error) {
+	path, err := s.path(propertyId, languageCode)
+	if err != nil {
+		return nil, &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusNotFound, URL: s.dir, Err: err}
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusNotFound, URL: path, Err: err}
+	}
+	if err != nil {
+		return nil, &UpstreamError{Kind: UpstreamErrorConfig, URL: path, Err: err}
+	}
+
+	var originalData map[string]interface{}
+	if err := json.Unmarshal(data, &originalData); err != nil {
+		return nil, &UpstreamError{Kind: UpstreamErrorDecode, URL: path, Err: err}
+	}
+	return originalData, nil
+}
+
+// Save records a document, replacing any earlier recording.
+func (s *fixtureStore) Save(propertyId, languageCode string, originalData map[string]interface{}) error {
+	path, err := s.path(propertyId, languageCode)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(originalData, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that replay never sees a partial fixture
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so that readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixtureStore(t *testing.T) {
+	store := &fixtureStore{dir: t.TempDir()}
+	document := map[string]interface{}{"S3": map[string]interface{}{"ID": "HA-1"}}
+
+	assert.NoError(t, store.Save("HA-1", "en", document))
+	_, err := os.Stat(filepath.Join(store.dir, "HA-1", "en.json"))
+	assert.NoError(t, err)
+
+	loaded, err := store.Load("HA-1", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, document, loaded)
+
+	_, err = store.Load("HA-1", "fr")
+	var upstreamErr *UpstreamError
+	assert.True(t, errors.As(err, &upstreamErr))
+	assert.Equal(t, http.StatusNotFound, upstreamErr.StatusCode)
+
+	// IDs cannot escape the fixtures directory
+	assert.NoError(t, store.Save("../HA-2", "en", document))
+	_, err = os.Stat(filepath.Join(store.dir, "..%2FHA-2", "en.json"))
+	assert.NoError(t, err)
+	assert.Error(t, store.Save("..", "en", document))
+}
+
+func TestUpstreamClientRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	upstream := fakeupstream.New(fakeupstream.FixturesDir(), fakeupstream.Scenario{}, 1)
+	server := httptest.NewServer(upstream)
+
+	web.AppConfig.Set("upstreamFixturesDir", dir)
+	web.AppConfig.Set("upstreamMode", "record")
+	web.AppConfig.Set("externalAPIBaseURL", server.URL)
+	defer web.AppConfig.Set("upstreamMode", "passthrough")
+	defer web.AppConfig.Set("upstreamFixturesDir", "fixtures")
+
+	client, err := DefaultUpstreamClient()
+	assert.NoError(t, err)
+	recorded, meta, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr", "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "en", meta.Language)
+	server.Close()
+
+	_, err = os.Stat(filepath.Join(dir, "HA-3213808988", "fr.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Replay needs neither the upstream nor its URL
+	web.AppConfig.Set("upstreamMode", "replay")
+	web.AppConfig.Set("externalAPIBaseURL", "")
+
+	client, err = DefaultUpstreamClient()
+	assert.NoError(t, err)
+	replayed, meta, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"fr", "en"})
+	assert.NoError(t, err)
+	assert.Equal(t, "en", meta.Language)
+	assert.Equal(t, recorded, replayed)
+	assert.Equal(t, 2, upstream.Requests())
+
+	_, _, err = client.FetchPropertyDocument(context.Background(), "456", []string{"en"})
+	assert.Equal(t, ErrorNotFound, ErrorCodeOf(newServiceError("456", err)))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightGroup lets concurrent callers asking for the same upstream document
+// share one in-flight request instead of each issuing their own.
+type inflightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	waiters  int
+	document map[string]interface{}
+	meta     FetchMeta
+	err      error
+}
+
+// Do runs fn once for all concurrent callers with the same key. shared reports
+// whether the result was produced by another caller's request.
+//
+// fn runs on its own context so that one caller going away does not fail the
+// others; that context is cancelled once every caller has stopped waiting.
+func (g *inflightGroup) Do(ctx context.Context, key string, fn func(context.Context) (map[string]interface{}, FetchMeta, error)) (document map[string]interface{}, meta FetchMeta, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.Background())
+		call = &inflightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			document, meta, err := fn(callCtx)
+
+			g.mu.Lock()
+			call.document, call.meta, call.err = document, meta, err
+			g.forget(key, call)
+			g.mu.Unlock()
+
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.document, call.meta, shared, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		abandoned := call.waiters == 0
+		if abandoned {
+			// Later callers must start a fresh request rather than join a cancelled one
+			g.forget(key, call)
+		}
+		g.mu.Unlock()
+
+		if abandoned {
+			call.cancel()
+		}
+		return nil, FetchMeta{}, shared, ctx.Err()
+	}
+}
+
+func (g *inflightGroup) forget(key string, call *inflightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWaiters blocks until n callers are waiting on the in-flight call for key.
+func waitForWaiters(t *testing.T, g *inflightGroup, key string, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		call, ok := g.calls[key]
+		joined := ok && call.waiters >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters on %s", n, key)
+}
+
+func TestInflightGroupDo(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    map[string]interface{}
+		err         error
+		expectError bool
+	}{
+		{
+			name:        "Shares the document",
+			document:    map[string]interface{}{"S3": "data"},
+			expectError: false,
+		},
+		{
+			name:        "Shares the error",
+			err:         errors.New("upstream failed"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var group inflightGroup
+			var calls int32
+			release := make(chan struct{})
+
+			var wg sync.WaitGroup
+			results := make([]map[string]interface{}, 5)
+			errs := make([]error, 5)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], _, _, errs[i] = group.Do(context.Background(), "123|en", func(ctx context.Context) (map[string]interface{}, FetchMeta, error) {
+						atomic.AddInt32(&calls, 1)
+						<-release
+						return tt.document, FetchMeta{Attempts: 1}, tt.err
+					})
+				}(i)
+			}
+
+			waitForWaiters(t, &group, "123|en", 5)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+			for i := range results {
+				if tt.expectError {
+					assert.Error(t, errs[i])
+				} else {
+					assert.NoError(t, errs[i])
+					assert.Equal(t, tt.document, results[i])
+				}
+			}
+			assert.Empty(t, group.calls)
+		})
+	}
+}
+
+func TestUpstreamClientCoalescesRequests(t *testing.T) {
+	upstream := fakeupstream.New(fakeupstream.FixturesDir(), fakeupstream.Scenario{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		upstream.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewUpstreamClient(server.URL, 2*time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+			assert.NoError(t, err)
+			assert.Contains(t, result, "S3")
+		}()
+	}
+
+	waitForWaiters(t, &client.inflight, documentCacheKey("HA-3213808988", "en"), 4)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, upstream.Requests())
+
+	// Later requests start a new upstream call
+	_, _, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, upstream.Requests())
+}
+
+func TestInflightGroupCallerCancellation(t *testing.T) {
+	var group inflightGroup
+	release := make(chan struct{})
+	fnCtxDone := make(chan struct{})
+
+	fn := func(ctx context.Context) (map[string]interface{}, FetchMeta, error) {
+		<-release
+		return map[string]interface{}{"S3": "data"}, FetchMeta{Attempts: 1}, nil
+	}
+
+	// One caller giving up does not affect the others
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, _, _, err := group.Do(leaderCtx, "123|en", fn)
+		leaderErr <- err
+	}()
+	waitForWaiters(t, &group, "123|en", 1)
+
+	followerResult := make(chan map[string]interface{})
+	go func() {
+		document, _, shared, err := group.Do(context.Background(), "123|en", fn)
+		assert.True(t, shared)
+		assert.NoError(t, err)
+		followerResult <- document
+	}()
+	waitForWaiters(t, &group, "123|en", 2)
+
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(release)
+	assert.Equal(t, map[string]interface{}{"S3": "data"}, <-followerResult)
+
+	// When every caller gives up the shared request is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _, err := group.Do(ctx, "456|en", func(ctx context.Context) (map[string]interface{}, FetchMeta, error) {
+			<-ctx.Done()
+			close(fnCtxDone)
+			return nil, FetchMeta{}, ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	waitForWaiters(t, &group, "456|en", 1)
+	cancel()
+	<-done
+
+	select {
+	case <-fnCtxDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("shared request was not cancelled")
+	}
+}
+
+func TestUpstreamClientContext(t *testing.T) {
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{LatencyMs: 2000})
+
+	tests := []struct {
+		name         string
+		ctx          func() (context.Context, context.CancelFunc)
+		expectedKind UpstreamErrorKind
+	}{
+		{
+			name: "Deadline exceeded",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			expectedKind: UpstreamErrorTimeout,
+		},
+		{
+			name: "Client disconnected",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			expectedKind: UpstreamErrorCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewUpstreamClient(url, 5*time.Second)
+			client.breaker = newCircuitBreaker(0.5, 1, time.Minute, 30*time.Second, 1)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, _, err := client.FetchPropertyDocument(ctx, "HA-3213808988", []string{"en"})
+
+			var upstreamErr *UpstreamError
+			assert.True(t, errors.As(err, &upstreamErr))
+			assert.Equal(t, tt.expectedKind, upstreamErr.Kind)
+			assert.Less(t, time.Since(start), time.Second)
+
+			// Abandoned requests are not held against the upstream
+			assert.Equal(t, BreakerClosed, client.breaker.State())
+		})
+	}
+}
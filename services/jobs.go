@@ -0,0 +1,433 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus is the state of a property fetch job.
+type JobStatus string
+
+const (
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobCanceled JobStatus = "canceled"
+)
+
+// ErrJobNotFound is returned for an unknown job ID.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobOptions are kept with a job so that it fetches the same way after a
+// restart.
+type JobOptions struct {
+	FetchOptions
+	Fields         FieldSet
+	WithProvenance bool
+}
+
+// JobFetchFunc fetches one property of a job.
+type JobFetchFunc func(ctx context.Context, id string, opts JobOptions) structs.BulkItem
+
+// jobState is saved as <dir>/<id>.json whenever the status changes. The
+// fetched items are appended to <dir>/<id>.ndjson, one jobResult per line,
+// so the results of a job only ever grow at the end.
+type jobState struct {
+	ID        string
+	Status    JobStatus
+	IDs       []string
+	Options   JobOptions
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type jobResult struct {
+	Index int
+	Item  structs.BulkItem
+}
+
+type job struct {
+	state   jobState
+	fetched map[int]bool
+	done    int
+	failed  int
+	results *os.File
+	// lines holds the offset of each line of results, so that a page is read
+	// without scanning the lines before it. size is the offset of the next.
+	lines    []int64
+	size     int64
+	cancel   context.CancelFunc
+	finished chan struct{}
+}
+
+// JobManager runs property fetch jobs in the background and keeps their
+// state and results on disk. Jobs that were running when the process stopped
+// resume, skipping the properties already fetched. Finished and canceled jobs
+// are deleted once they have not changed for the retention period.
+type JobManager struct {
+	mu        sync.Mutex
+	dir       string
+	workers   int
+	retention time.Duration
+	fetch     JobFetchFunc
+	jobs      map[string]*job
+	now       func() time.Time
+}
+
+// NewJobManager loads the jobs saved in dir and resumes the running ones.
+// Each job fetches up to workers properties at a time. A retention of 0 keeps
+// finished jobs forever.
+func NewJobManager(dir string, workers int, retention time.Duration, fetch JobFetchFunc) (*JobManager, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &JobManager{dir: dir, workers: workers, retention: retention, fetch: fetch, jobs: map[string]*job{}, now: time.Now}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		j, err := m.load(path)
+		if err != nil {
+			log.Printf("skipping job %s: %v", path, err)
+			continue
+		}
+		m.jobs[j.state.ID] = j
+		if j.state.Status == JobRunning {
+			log.Printf("resuming job %s: %d of %d properties left", j.state.ID, len(j.state.IDs)-len(j.fetched), len(j.state.IDs))
+			m.run(j)
+		} else {
+			j.results.Close()
+			close(j.finished)
+		}
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.mu.Unlock()
+	return m, nil
+}
+
+// Start creates a job fetching ids and starts it.
+func (m *JobManager) Start(ids []string, opts JobOptions) (structs.JobProgress, error) {
+	id, err := newJobID()
+	if err != nil {
+		return structs.JobProgress{}, err
+	}
+
+	now := m.now()
+	j := &job{
+		state:    jobState{ID: id, Status: JobRunning, IDs: ids, Options: opts, CreatedAt: now, UpdatedAt: now},
+		fetched:  map[int]bool{},
+		finished: make(chan struct{}),
+	}
+	if err := m.saveState(j); err != nil {
+		return structs.JobProgress{}, err
+	}
+	if j.results, err = os.OpenFile(m.resultsPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		if err := os.Remove(m.statePath(id)); err != nil {
+			log.Printf("failed to delete job %s: %v", id, err)
+		}
+		return structs.JobProgress{}, err
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.jobs[id] = j
+	progress := j.progress()
+	m.mu.Unlock()
+
+	m.run(j)
+	return progress, nil
+}
+
+// Progress reports how far a job has got.
+func (m *JobManager) Progress(id string) (structs.JobProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return structs.JobProgress{}, ErrJobNotFound
+	}
+	return j.progress(), nil
+}
+
+// Cancel stops a running job. The items fetched so far are kept; canceling a
+// finished job changes nothing.
+func (m *JobManager) Cancel(id string) (structs.JobProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return structs.JobProgress{}, ErrJobNotFound
+	}
+	if j.state.Status == JobRunning {
+		j.state.Status = JobCanceled
+		j.state.UpdatedAt = m.now()
+		if err := m.saveState(j); err != nil {
+			log.Printf("failed to save job %s: %v", id, err)
+		}
+		j.cancel()
+	}
+	return j.progress(), nil
+}
+
+// Results returns up to limit of the items fetched by a job, starting at
+// offset, in the order they were fetched. Items are only ever added after the
+// last one, so a page never changes once it is full.
+func (m *JobManager) Results(id string, offset, limit int) (structs.JobResultsPage, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	var available int
+	var start, end int64
+	if ok {
+		available = len(j.lines)
+		if offset < available {
+			start, end = j.lines[offset], j.size
+			if offset+limit < available {
+				end = j.lines[offset+limit]
+			}
+		}
+	}
+	m.mu.Unlock()
+	if !ok {
+		return structs.JobResultsPage{}, ErrJobNotFound
+	}
+
+	page := structs.JobResultsPage{JobID: id, Offset: offset, Limit: limit, Items: []structs.BulkItem{}}
+	if offset >= available {
+		return page, nil
+	}
+
+	file, err := os.Open(m.resultsPath(id))
+	if err != nil {
+		return page, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(io.NewSectionReader(file, start, end-start))
+	for offset+len(page.Items) < available && len(page.Items) < limit {
+		data, err := reader.ReadBytes('\n')
+		if err != nil {
+			return page, err
+		}
+		var result jobResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, result.Item)
+	}
+
+	if next := offset + len(page.Items); next < available {
+		page.NextOffset = &next
+	}
+	return page, nil
+}
+
+// run fetches the properties of j that have not been fetched yet.
+func (m *JobManager) run(j *job) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	j.cancel = cancel
+	var pending []int
+	var pendingIDs []string
+	for i, id := range j.state.IDs {
+		if !j.fetched[i] {
+			pending = append(pending, i)
+			pendingIDs = append(pendingIDs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		forEachProperty(ctx, pendingIDs, m.workers, func(k int, id string) {
+			item := m.fetch(ctx, id, j.state.Options)
+			if ctx.Err() != nil {
+				// Canceled while fetching; the item says nothing about the property.
+				return
+			}
+			m.record(j, pending[k], item)
+		})
+		m.finish(j)
+	}()
+}
+
+// record appends a fetched item to the results of j.
+func (m *JobManager) record(j *job, index int, item structs.BulkItem) {
+	data, err := json.Marshal(jobResult{Index: index, Item: item})
+	if err != nil {
+		log.Printf("failed to encode result of job %s: %v", j.state.ID, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := j.results.Write(append(data, '\n'))
+	if err != nil {
+		log.Printf("failed to save result of job %s: %v", j.state.ID, err)
+		return
+	}
+	j.addLine(int64(n))
+	j.count(index, item)
+	j.state.UpdatedAt = m.now()
+}
+
+// finish marks j done unless it was canceled.
+func (m *JobManager) finish(j *job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if j.state.Status == JobRunning {
+		j.state.Status = JobDone
+		j.state.UpdatedAt = m.now()
+		if err := m.saveState(j); err != nil {
+			log.Printf("failed to save job %s: %v", j.state.ID, err)
+		}
+	}
+	if err := j.results.Close(); err != nil {
+		log.Printf("failed to close results of job %s: %v", j.state.ID, err)
+	}
+	close(j.finished)
+}
+
+// prune deletes the finished and canceled jobs that have not changed for the
+// retention period, from memory and from disk. m.mu must be held.
+func (m *JobManager) prune() {
+	if m.retention <= 0 {
+		return
+	}
+	expired := m.now().Add(-m.retention)
+	for id, j := range m.jobs {
+		if j.state.Status == JobRunning || !j.state.UpdatedAt.Before(expired) {
+			continue
+		}
+		for _, path := range []string{m.statePath(id), m.resultsPath(id)} {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("failed to delete job %s: %v", id, err)
+			}
+		}
+		delete(m.jobs, id)
+	}
+}
+
+// load reads a saved job and the items it fetched. A result line cut short
+// by a crash is dropped so that new results are appended after the last
+// complete one.
+func (m *JobManager) load(path string) (*job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	j := &job{fetched: map[int]bool{}, finished: make(chan struct{})}
+	if err := json.Unmarshal(data, &j.state); err != nil {
+		return nil, err
+	}
+	if j.state.ID != strings.TrimSuffix(filepath.Base(path), ".json") {
+		return nil, fmt.Errorf("job file holds job %q", j.state.ID)
+	}
+
+	j.results, err = os.OpenFile(m.resultsPath(j.state.ID), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	var complete int64
+	reader := bufio.NewReader(j.results)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				j.results.Close()
+				return nil, err
+			}
+			break
+		}
+		var result jobResult
+		if err := json.Unmarshal(line, &result); err != nil || result.Index < 0 || result.Index >= len(j.state.IDs) {
+			break
+		}
+		complete += int64(len(line))
+		j.addLine(int64(len(line)))
+		j.count(result.Index, result.Item)
+	}
+
+	if err := j.results.Truncate(complete); err != nil {
+		j.results.Close()
+		return nil, err
+	}
+	if _, err := j.results.Seek(complete, io.SeekStart); err != nil {
+		j.results.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (m *JobManager) saveState(j *job) error {
+	data, err := json.Marshal(j.state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.statePath(j.state.ID), data)
+}
+
+func (m *JobManager) statePath(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
+
+func (m *JobManager) resultsPath(id string) string {
+	return filepath.Join(m.dir, id+".ndjson")
+}
+
+// addLine indexes a result line of n bytes written at the end of results.
+func (j *job) addLine(n int64) {
+	j.lines = append(j.lines, j.size)
+	j.size += n
+}
+
+func (j *job) count(index int, item structs.BulkItem) {
+	j.fetched[index] = true
+	if item.Status == structs.BulkItemOK {
+		j.done++
+	} else {
+		j.failed++
+	}
+}
+
+func (j *job) progress() structs.JobProgress {
+	return structs.JobProgress{
+		ID:        j.state.ID,
+		Status:    string(j.state.Status),
+		Total:     len(j.state.IDs),
+		Done:      j.done,
+		Failed:    j.failed,
+		Pending:   len(j.state.IDs) - j.done - j.failed,
+		CreatedAt: j.state.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: j.state.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
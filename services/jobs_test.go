@@ -0,0 +1,240 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJobFetch reports IDs starting with "missing" as not found and records
+// every ID it was asked for.
+type fakeJobFetch struct {
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *fakeJobFetch) fetch(ctx context.Context, id string, opts JobOptions) structs.BulkItem {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, id)
+	f.mu.Unlock()
+	if len(id) >= 7 && id[:7] == "missing" {
+		return structs.BulkItem{ID: id, Status: structs.BulkItemNotFound}
+	}
+	return structs.BulkItem{ID: id, Status: structs.BulkItemOK, Data: opts.Language}
+}
+
+func waitForJob(t *testing.T, m *JobManager, id string) {
+	m.mu.Lock()
+	j := m.jobs[id]
+	m.mu.Unlock()
+	<-j.finished
+}
+
+func TestJobManager(t *testing.T) {
+	dir := t.TempDir()
+	fake := &fakeJobFetch{}
+	m, err := NewJobManager(dir, 2, 0, fake.fetch)
+	assert.NoError(t, err)
+
+	started, err := m.Start([]string{"HA-1", "missing-2", "HA-3", "HA-4", "missing-5"}, JobOptions{FetchOptions: FetchOptions{Language: "fr"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "running", started.Status)
+	assert.Equal(t, 5, started.Total)
+	waitForJob(t, m, started.ID)
+
+	progress, err := m.Progress(started.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", progress.Status)
+	assert.Equal(t, 3, progress.Done)
+	assert.Equal(t, 2, progress.Failed)
+	assert.Equal(t, 0, progress.Pending)
+
+	first, err := m.Results(started.ID, 0, 3)
+	assert.NoError(t, err)
+	assert.Len(t, first.Items, 3)
+	assert.Equal(t, 3, *first.NextOffset)
+
+	rest, err := m.Results(started.ID, *first.NextOffset, 3)
+	assert.NoError(t, err)
+	assert.Len(t, rest.Items, 2)
+	assert.Nil(t, rest.NextOffset)
+
+	var ids []string
+	for _, item := range append(first.Items, rest.Items...) {
+		ids = append(ids, item.ID)
+	}
+	assert.ElementsMatch(t, []string{"HA-1", "missing-2", "HA-3", "HA-4", "missing-5"}, ids)
+
+	past, err := m.Results(started.ID, 10, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, past.Items)
+
+	// A finished job is loaded as is after a restart.
+	reloaded, err := NewJobManager(dir, 2, 0, fake.fetch)
+	assert.NoError(t, err)
+	progress, err = reloaded.Progress(started.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", progress.Status)
+	assert.Equal(t, 3, progress.Done)
+	assert.Len(t, fake.fetched, 5)
+}
+
+func TestJobManagerUnknownJob(t *testing.T) {
+	m, err := NewJobManager(t.TempDir(), 2, 0, (&fakeJobFetch{}).fetch)
+	assert.NoError(t, err)
+
+	_, err = m.Progress("nope")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = m.Results("nope", 0, 10)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = m.Cancel("nope")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestJobManagerResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	state := `{"ID":"job1","Status":"running","IDs":["HA-1","HA-2","HA-3"],"Options":{"Language":"en","Source":"os","Fields":null,"WithProvenance":false},"CreatedAt":"2025-01-09T06:11:56Z","UpdatedAt":"2025-01-09T06:11:56Z"}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "job1.json"), []byte(state), 0o644))
+	// HA-2 was fetched; the write of HA-1 was cut short by the crash.
+	results := `{"Index":1,"Item":{"ID":"HA-2","Status":"ok","LatencyMs":1}}` + "\n" + `{"Index":0,"Item":{"ID":"HA-`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "job1.ndjson"), []byte(results), 0o644))
+
+	fake := &fakeJobFetch{}
+	m, err := NewJobManager(dir, 2, 0, fake.fetch)
+	assert.NoError(t, err)
+	waitForJob(t, m, "job1")
+
+	assert.ElementsMatch(t, []string{"HA-1", "HA-3"}, fake.fetched)
+
+	progress, err := m.Progress("job1")
+	assert.NoError(t, err)
+	assert.Equal(t, "done", progress.Status)
+	assert.Equal(t, 3, progress.Done)
+
+	page, err := m.Results("job1", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 3)
+	assert.Equal(t, "HA-2", page.Items[0].ID)
+
+	// Pages after the loaded results start at the items fetched since.
+	rest, err := m.Results("job1", 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, page.Items[1:], rest.Items)
+}
+
+func TestJobManagerResultsWhileRunning(t *testing.T) {
+	release := map[string]chan struct{}{"HA-1": make(chan struct{}), "HA-2": make(chan struct{})}
+	fetch := func(ctx context.Context, id string, opts JobOptions) structs.BulkItem {
+		<-release[id]
+		return structs.BulkItem{ID: id, Status: structs.BulkItemOK}
+	}
+	m, err := NewJobManager(t.TempDir(), 2, 0, fetch)
+	assert.NoError(t, err)
+	job, err := m.Start([]string{"HA-1", "HA-2"}, JobOptions{})
+	assert.NoError(t, err)
+
+	waitForResults := func(n int) {
+		for {
+			progress, err := m.Progress(job.ID)
+			assert.NoError(t, err)
+			if progress.Done == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	close(release["HA-2"])
+	waitForResults(1)
+	first, err := m.Results(job.ID, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []structs.BulkItem{{ID: "HA-2", Status: structs.BulkItemOK}}, first.Items)
+	assert.Nil(t, first.NextOffset)
+
+	// Later items are added after the page read so far, which stays the same.
+	close(release["HA-1"])
+	waitForJob(t, m, job.ID)
+	page, err := m.Results(job.ID, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Items, page.Items)
+	assert.Equal(t, 1, *page.NextOffset)
+	rest, err := m.Results(job.ID, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []structs.BulkItem{{ID: "HA-1", Status: structs.BulkItemOK}}, rest.Items)
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{}, 10)
+	blocking := func(ctx context.Context, id string, opts JobOptions) structs.BulkItem {
+		started <- struct{}{}
+		<-ctx.Done()
+		return structs.BulkItem{ID: id, Status: structs.BulkItemError}
+	}
+
+	m, err := NewJobManager(dir, 2, 0, blocking)
+	assert.NoError(t, err)
+	job, err := m.Start([]string{"HA-1", "HA-2", "HA-3", "HA-4"}, JobOptions{})
+	assert.NoError(t, err)
+	<-started
+
+	canceled, err := m.Cancel(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "canceled", canceled.Status)
+	waitForJob(t, m, job.ID)
+
+	progress, err := m.Progress(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "canceled", progress.Status)
+	assert.Equal(t, 0, progress.Failed)
+	assert.Equal(t, 4, progress.Pending)
+
+	// A canceled job is not resumed after a restart.
+	reloaded, err := NewJobManager(dir, 2, 0, (&fakeJobFetch{}).fetch)
+	assert.NoError(t, err)
+	progress, err = reloaded.Progress(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "canceled", progress.Status)
+	assert.Equal(t, 4, progress.Pending)
+}
+
+func TestJobManagerRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m, err := NewJobManager(dir, 2, time.Hour, (&fakeJobFetch{}).fetch)
+	assert.NoError(t, err)
+	m.now = func() time.Time { return now }
+
+	old, err := m.Start([]string{"HA-1"}, JobOptions{})
+	assert.NoError(t, err)
+	waitForJob(t, m, old.ID)
+
+	// Starting a job after the retention period deletes the finished one.
+	now = now.Add(2 * time.Hour)
+	recent, err := m.Start([]string{"HA-2"}, JobOptions{})
+	assert.NoError(t, err)
+	waitForJob(t, m, recent.ID)
+
+	_, err = m.Progress(old.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	for _, name := range []string{old.ID + ".json", old.ID + ".ndjson"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+	_, err = m.Progress(recent.ID)
+	assert.NoError(t, err)
+
+	// Expired jobs are also deleted on startup.
+	reloaded, err := NewJobManager(dir, 2, time.Nanosecond, (&fakeJobFetch{}).fetch)
+	assert.NoError(t, err)
+	_, err = reloaded.Progress(recent.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = os.Stat(filepath.Join(dir, recent.ID+".json"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// languageChain lists the languages to try for a request, in order: the
+// requested language, its configured fallbacks, its primary subtag and
+// finally defaultLanguage.
+//
+// languageFallbacks = "fr-CA:fr,en;pt-BR:pt,es"
+func languageChain(languageCode string) []string {
+	defaultLanguage := web.AppConfig.DefaultString("defaultLanguage", "en")
+	if languageCode == "" {
+		languageCode = defaultLanguage
+	}
+
+	chain := []string{languageCode}
+	for _, entry := range web.AppConfig.DefaultStrings("languageFallbacks", nil) {
+		lang, fallbacks, ok := strings.Cut(entry, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(lang), languageCode) {
+			continue
+		}
+		for _, fallback := range strings.Split(fallbacks, ",") {
+			chain = appendLanguage(chain, strings.TrimSpace(fallback))
+		}
+	}
+	if base, _, ok := strings.Cut(languageCode, "-"); ok {
+		chain = appendLanguage(chain, base)
+	}
+
+	return appendLanguage(chain, defaultLanguage)
+}
+
+func appendLanguage(chain []string, languageCode string) []string {
+	if languageCode == "" {
+		return chain
+	}
+	for _, lang := range chain {
+		if strings.EqualFold(lang, languageCode) {
+			return chain
+		}
+	}
+	return append(chain, languageCode)
+}
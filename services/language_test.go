@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageChain(t *testing.T) {
+	web.AppConfig.Set("languageFallbacks", "fr-CA:fr-FR,fr;pt-BR:es")
+	defer web.AppConfig.Set("languageFallbacks", "")
+
+	tests := []struct {
+		name     string
+		language string
+		expected []string
+	}{
+		{name: "Empty uses the default", language: "", expected: []string{"en"}},
+		{name: "Default language", language: "en", expected: []string{"en"}},
+		{name: "Configured fallbacks", language: "fr-CA", expected: []string{"fr-CA", "fr-FR", "fr", "en"}},
+		{name: "Primary subtag after fallbacks", language: "pt-BR", expected: []string{"pt-BR", "es", "pt", "en"}},
+		{name: "Unconfigured language", language: "de", expected: []string{"de", "en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, languageChain(tt.language))
+		})
+	}
+}
+
+func TestUpstreamClientLanguageFallback(t *testing.T) {
+	// TEST-LANG has an fr and an en document, and an empty de one
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{})
+
+	tests := []struct {
+		name             string
+		languages        []string
+		expectedLanguage string
+		expectedAttempts int
+	}{
+		{name: "Requested language available", languages: []string{"fr", "en"}, expectedLanguage: "fr", expectedAttempts: 1},
+		{name: "Not found falls back", languages: []string{"fr-CA", "fr", "en"}, expectedLanguage: "fr", expectedAttempts: 2},
+		{name: "Empty document falls back", languages: []string{"de", "en"}, expectedLanguage: "en", expectedAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewUpstreamClient(url, time.Second)
+
+			result, meta, err := client.FetchPropertyDocument(context.Background(), "TEST-LANG", tt.languages)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLanguage, meta.Language)
+			assert.Equal(t, tt.expectedAttempts, meta.Attempts)
+			assert.Equal(t, tt.expectedLanguage, result["S3"].(map[string]interface{})["ID"])
+		})
+	}
+}
@@ -2,153 +2,132 @@ package services
 
 import (
 	"beego-api-service/structs"
-	"encoding/json"
-	"errors"
-	"fmt"
+	"context"
 	"log"
-	"net/http"
-
-	"github.com/beego/beego/v2/server/web"
 )
 
-func FetchPropertyDetails(propertyId string) (structs.PropertyDetailsResponse, error) {
-	var transformedData structs.PropertyDetailsResponse
+func FetchPropertyDetails(ctx context.Context, propertyId string, opts FetchOptions) (structs.PropertyDetailsResponse, FetchMeta, error) {
+	return fetchDetails(ctx, propertyId, opts, SourceS3, nil)
+}
 
-	externalAPIBaseURL, err := web.AppConfig.String("externalAPIBaseURL")
+// FetchPropertyDetailsWithProvenance is FetchPropertyDetails that also
+// reports which upstream block and key each field was read from.
+func FetchPropertyDetailsWithProvenance(ctx context.Context, propertyId string, opts FetchOptions) (structs.PropertyDetailsWithProvenance, FetchMeta, error) {
+	provenance := structs.Provenance{}
+	transformedData, meta, err := fetchDetails(ctx, propertyId, opts, SourceS3, provenance)
 	if err != nil {
-		log.Printf("failed to load external API URL from config: %v", err)
-		return transformedData, err
+		return structs.PropertyDetailsWithProvenance{Details: transformedData}, meta, err
 	}
+	return structs.PropertyDetailsWithProvenance{Details: transformedData, Provenance: provenance}, meta, nil
+}
+
+// fetchDetails builds the property details from the source chosen in opts,
+// or defaultSource if none was chosen.
+func fetchDetails(ctx context.Context, propertyId string, opts FetchOptions, defaultSource Source, provenance structs.Provenance) (structs.PropertyDetailsResponse, FetchMeta, error) {
+	var transformedData structs.PropertyDetailsResponse
 
-	externalAPIURL := fmt.Sprintf("%s?propertyId=%s&languageCode=en", externalAPIBaseURL, propertyId)
-	resp, err := http.Get(externalAPIURL)
+	originalData, meta, err := fetchDocument(ctx, propertyId, opts)
 	if err != nil {
-		log.Printf("HTTP request failed: %v", err)
-		return transformedData, err
+		return transformedData, meta, err
 	}
-	defer resp.Body.Close()
 
-	var originalData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&originalData); err != nil {
-		log.Printf("failed to decode response: %v", err)
-		return transformedData, err
+	source := opts.Source
+	if source == "" {
+		source = defaultSource
 	}
-
-	if err := transformData(originalData, &transformedData); err != nil {
+	if err := transformSource(originalData, source, &transformedData, provenance); err != nil {
 		log.Printf("failed to transform data: %v", err)
-		return transformedData, err
+		return transformedData, meta, newPayloadError(propertyId, err)
 	}
 
-	return transformedData, nil
+	return transformedData, meta, nil
 }
 
+// transformData decodes the S3 block of the upstream document. Every field
+// that is missing or has the wrong type is reported in a *ValidationError;
+// transformedData is only written when the whole block is valid.
 func transformData(originalData map[string]interface{}, transformedData *structs.PropertyDetailsResponse) error {
-	s3Data, ok := originalData["S3"].(map[string]interface{})
-	if !ok {
-		log.Printf("invalid S3 data: expected map[string]interface{}, got %T", originalData["S3"])
-		return errors.New("invalid S3 data")
-	}
+	var d documentDecoder
+	var result structs.PropertyDetailsResponse
+
+	s3Data, _ := d.Object(d.root(originalData), "S3", required)
 
-	transformedData.ID = s3Data["ID"].(string)
-	transformedData.Feed = int(s3Data["Feed"].(float64))
-	transformedData.Published = s3Data["Published"].(bool)
+	result.ID = d.String(s3Data, "ID", required)
+	result.Feed = d.Int(s3Data, "Feed", required)
+	result.Published = d.Bool(s3Data, "Published", required)
 
-	geoInfo := s3Data["GeoInfo"].(map[string]interface{})
-	for _, category := range geoInfo["Categories"].([]interface{}) {
-		cat := category.(map[string]interface{})
-		transformedData.GeoInfo.Categories = append(transformedData.GeoInfo.Categories, struct {
+	geoInfo, _ := d.Object(s3Data, "GeoInfo", required)
+	for _, cat := range d.Objects(geoInfo, "Categories", optional) {
+		result.GeoInfo.Categories = append(result.GeoInfo.Categories, struct {
 			Name       string   `json:"Name"`
 			Slug       string   `json:"Slug"`
 			Type       string   `json:"Type"`
 			Display    []string `json:"Display"`
 			LocationID string   `json:"LocationID"`
 		}{
-			Name: cat["Name"].(string),
-			Slug: cat["Slug"].(string),
-			Type: cat["Type"].(string),
-			Display: func() []string {
-				display := []string{}
-				for _, d := range cat["Display"].([]interface{}) {
-					display = append(display, d.(string))
-				}
-				return display
-			}(),
-			LocationID: cat["LocationID"].(string),
+			Name:       d.String(cat, "Name", required),
+			Slug:       d.String(cat, "Slug", optional),
+			Type:       d.String(cat, "Type", optional),
+			Display:    d.Strings(cat, "Display", optional),
+			LocationID: d.String(cat, "LocationID", optional),
 		})
 	}
 
-	transformedData.GeoInfo.City = geoInfo["City"].(string)
-	transformedData.GeoInfo.Country = geoInfo["Country"].(string)
-	transformedData.GeoInfo.CountryCode = geoInfo["CountryCode"].(string)
-	transformedData.GeoInfo.Display = geoInfo["Display"].(string)
-	transformedData.GeoInfo.LocationID = geoInfo["LocationID"].(string)
-	transformedData.GeoInfo.StateAbbr = geoInfo["StateAbbr"].(string)
-	transformedData.GeoInfo.Lat = geoInfo["Lat"].(string)
-	transformedData.GeoInfo.Lng = geoInfo["Lng"].(string)
-
-	property := s3Data["Property"].(map[string]interface{})
-	transformedData.Property.Amenities = func() map[string]string {
-		amenities := map[string]string{}
-		for k, v := range property["Amenities"].(map[string]interface{}) {
-			amenities[k] = v.(string)
-		}
-		return amenities
-	}()
-	counts := property["Counts"].(map[string]interface{})
-	transformedData.Property.Counts.Bedroom = int(counts["Bedroom"].(float64))
-	transformedData.Property.Counts.Bathroom = int(counts["Bathroom"].(float64))
-	transformedData.Property.Counts.Reviews = int(counts["Reviews"].(float64))
-	transformedData.Property.Counts.Occupancy = int(counts["Occupancy"].(float64))
-
-	transformedData.Property.EcoFriendly = property["EcoFriendly"].(bool)
-	transformedData.Property.FeatureImage = property["FeatureImage"].(string)
-
-	if image, ok := property["Image"].(map[string]interface{}); ok {
-		if len(image) > 0 {
-			imgStruct := &struct {
-				Count  int      `json:"Count,omitempty"`
-				Images []string `json:"Images,omitempty"`
-			}{
-				Count:  int(image["Count"].(float64)),
-				Images: make([]string, len(image["Images"].([]interface{}))),
-			}
-			for i, img := range image["Images"].([]interface{}) {
-				imgStruct.Images[i] = img.(string)
-			}
-			transformedData.Property.Image = imgStruct
-		} else {
-			transformedData.Property.Image = nil
+	result.GeoInfo.City = d.String(geoInfo, "City", optional)
+	result.GeoInfo.Country = d.String(geoInfo, "Country", optional)
+	result.GeoInfo.CountryCode = d.String(geoInfo, "CountryCode", optional)
+	result.GeoInfo.Display = d.String(geoInfo, "Display", optional)
+	result.GeoInfo.LocationID = d.String(geoInfo, "LocationID", optional)
+	result.GeoInfo.StateAbbr = d.String(geoInfo, "StateAbbr", optional)
+	result.GeoInfo.Lat = d.String(geoInfo, "Lat", optional)
+	result.GeoInfo.Lng = d.String(geoInfo, "Lng", optional)
+
+	property, _ := d.Object(s3Data, "Property", required)
+	result.Property.Amenities = d.StringMap(property, "Amenities", optional)
+	counts, _ := d.Object(property, "Counts", optional)
+	result.Property.Counts.Bedroom = d.Int(counts, "Bedroom", optional)
+	result.Property.Counts.Bathroom = d.Int(counts, "Bathroom", optional)
+	result.Property.Counts.Reviews = d.Int(counts, "Reviews", optional)
+	result.Property.Counts.Occupancy = d.Int(counts, "Occupancy", optional)
+
+	result.Property.EcoFriendly = d.Bool(property, "EcoFriendly", optional)
+	result.Property.FeatureImage = d.String(property, "FeatureImage", optional)
+
+	if image, ok := d.Object(property, "Image", optional); ok && len(image.fields) > 0 {
+		result.Property.Image = &struct {
+			Count  int      `json:"Count,omitempty"`
+			Images []string `json:"Images,omitempty"`
+		}{
+			Count:  d.Int(image, "Count", optional),
+			Images: d.Strings(image, "Images", optional),
 		}
 	}
 
-	transformedData.Property.Price = int(property["Price"].(float64))
-	transformedData.Property.PropertyName = property["PropertyName"].(string)
-	transformedData.Property.PropertySlug = property["PropertySlug"].(string)
-	transformedData.Property.PropertyType = property["PropertyType"].(string)
-	transformedData.Property.PropertyTypeCategoryId = property["PropertyTypeCategoryId"].(string)
-	transformedData.Property.ReviewScore = int(property["ReviewScore"].(float64))
-	transformedData.Property.ReviewScores = func() map[string]float64 {
-		reviewScores := map[string]float64{}
-		for k, v := range property["ReviewScores"].(map[string]interface{}) {
-			reviewScores[k] = v.(float64)
-		}
-		return reviewScores
-	}()
-	transformedData.Property.RoomSize = property["RoomSize"].(float64)
-	transformedData.Property.MinStay = int(property["MinStay"].(float64))
-	transformedData.Property.UpdatedAt = property["UpdatedAt"].(string)
-
-	partner := s3Data["Partner"].(map[string]interface{})
-	transformedData.Partner.ID = partner["ID"].(string)
-	for _, archived := range partner["Archived"].([]interface{}) {
-		transformedData.Partner.Archived = append(transformedData.Partner.Archived, archived.(string))
+	result.Property.Price = d.Int(property, "Price", optional)
+	result.Property.PropertyName = d.String(property, "PropertyName", required)
+	result.Property.PropertySlug = d.String(property, "PropertySlug", optional)
+	result.Property.PropertyType = d.String(property, "PropertyType", optional)
+	result.Property.PropertyTypeCategoryId = d.String(property, "PropertyTypeCategoryId", optional)
+	result.Property.ReviewScore = d.Int(property, "ReviewScore", optional)
+	result.Property.ReviewScores = d.FloatMap(property, "ReviewScores", optional)
+	result.Property.RoomSize = d.Float(property, "RoomSize", optional)
+	result.Property.MinStay = d.Int(property, "MinStay", optional)
+	result.Property.UpdatedAt = d.String(property, "UpdatedAt", optional)
+
+	partner, _ := d.Object(s3Data, "Partner", required)
+	result.Partner.ID = d.String(partner, "ID", required)
+	result.Partner.Archived = d.Strings(partner, "Archived", optional)
+	result.Partner.OwnerID = d.String(partner, "OwnerID", optional)
+	result.Partner.HcomID = d.String(partner, "HcomID", optional)
+	result.Partner.BrandId = d.String(partner, "BrandId", optional)
+	result.Partner.URL = d.String(partner, "URL", optional)
+	result.Partner.UnitNumber = d.String(partner, "UnitNumber", optional)
+	result.Partner.EpCluster = d.String(partner, "EpCluster", optional)
+
+	if err := d.Err(); err != nil {
+		return err
 	}
-	transformedData.Partner.OwnerID = partner["OwnerID"].(string)
-	transformedData.Partner.HcomID = partner["HcomID"].(string)
-	transformedData.Partner.BrandId = partner["BrandId"].(string)
-	transformedData.Partner.URL = partner["URL"].(string)
-	transformedData.Partner.UnitNumber = partner["UnitNumber"].(string)
-	transformedData.Partner.EpCluster = partner["EpCluster"].(string)
 
+	*transformedData = result
 	return nil
 }
@@ -1,38 +1,23 @@
 package services
 
 import (
+	"beego-api-service/internal/fakeupstream"
 	"beego-api-service/structs"
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
-	"net/http/httptest"
 	"testing"
-	"time"
 
 	"github.com/beego/beego/v2/server/web"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestFetchPropertyDetails(t *testing.T) {
-	// Mock server to simulate external API
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Verify request parameters
-		assert.Equal(t, "en", r.URL.Query().Get("languageCode"))
-		propertyID := r.URL.Query().Get("propertyId")
-
-		switch propertyID {
-		case "valid123":
-			json.NewEncoder(w).Encode(getMockValidResponse(time.Now().UTC().Format(time.RFC3339)))
-		case "invalid456":
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid property ID"})
-		default:
-			w.WriteHeader(http.StatusNotFound)
-		}
-	}))
-	defer mockServer.Close()
-
-	// Set mock server URL in app config
-	web.AppConfig.Set("externalAPIBaseURL", mockServer.URL)
+	// The fake upstream rejects invalid456 like the external API does
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{
+		Properties: map[string]fakeupstream.Scenario{"invalid456": {ErrorRate: 1, ErrorStatus: http.StatusBadRequest}},
+	})
+	web.AppConfig.Set("externalAPIBaseURL", url)
 
 	tests := []struct {
 		name           string
@@ -42,7 +27,7 @@ func TestFetchPropertyDetails(t *testing.T) {
 	}{
 		{
 			name:          "Valid property ID",
-			propertyID:    "valid123",
+			propertyID:    "TEST-S3",
 			expectedError: false,
 			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
 				assert.NotEmpty(t, result.ID)
@@ -69,7 +54,7 @@ func TestFetchPropertyDetails(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := FetchPropertyDetails(tt.propertyID)
+			result, _, err := FetchPropertyDetails(context.Background(), tt.propertyID, FetchOptions{})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -82,7 +67,8 @@ func TestFetchPropertyDetails(t *testing.T) {
 	}
 }
 
-// Helper function to get mock valid response
+// getMockValidResponse is the document of the TEST-S3 fixture, which was
+// updated at 2025-01-09T06:11:56Z.
 func getMockValidResponse(currentTime string) map[string]interface{} {
 	return map[string]interface{}{
 		"S3": map[string]interface{}{
@@ -153,102 +139,166 @@ func getMockValidResponse(currentTime string) map[string]interface{} {
 	}
 }
 
-// func TestTransformData(t *testing.T) {
-// 	currentTime := "2025-01-09T06:11:56Z"
-
-// 	tests := []struct {
-// 		name           string
-// 		input          map[string]interface{}
-// 		expectedError  bool
-// 		validateResult func(*testing.T, structs.PropertyDetailsResponse)
-// 	}{
-// 		{
-// 			name:          "Valid complete data",
-// 			input:         getMockValidResponse(currentTime),
-// 			expectedError: false,
-// 			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
-// 				assert.Equal(t, "TEST123", result.ID)
-// 				assert.Equal(t, 1, result.Feed)
-// 				assert.True(t, result.Published)
-
-// 				// Validate GeoInfo
-// 				assert.Equal(t, "Test City", result.GeoInfo.City)
-// 				assert.Equal(t, "Test Country", result.GeoInfo.Country)
-// 				assert.Len(t, result.GeoInfo.Categories, 1)
-
-// 				// Validate Property details
-// 				assert.Equal(t, "Test Property", result.Property.PropertyName)
-// 				assert.Equal(t, 100, result.Property.Price)
-// 				assert.Equal(t, 2, result.Property.Counts.Bedroom)
-// 				assert.Equal(t, 2, result.Property.Counts.Bathroom)
-
-// 				// Validate Partner details
-// 				assert.Equal(t, "PARTNER123", result.Partner.ID)
-// 				assert.Contains(t, result.Partner.Archived, "archived1")
-// 			},
-// 		},
-// 		{
-// 			name: "Missing S3 data",
-// 			input: map[string]interface{}{
-// 				"other": "data",
-// 			},
-// 			expectedError: true,
-// 			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
-// 				assert.Empty(t, result)
-// 			},
-// 		},
-// 		{
-// 			name: "Invalid data types",
-// 			input: map[string]interface{}{
-// 				"S3": map[string]interface{}{
-// 					"ID":        123,    // Invalid: should be string
-// 					"Feed":      "1",    // Invalid: should be number
-// 					"Published": "true", // Invalid: should be boolean
-// 					"GeoInfo": map[string]interface{}{
-// 						"City":    456,  // Invalid: should be string
-// 						"Country": true, // Invalid: should be string
-// 					},
-// 					"Property": map[string]interface{}{
-// 						"Price": "invalid", // Invalid: should be number
-// 					},
-// 				},
-// 			},
-// 			expectedError: true,
-// 			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
-// 				assert.Empty(t, result)
-// 			},
-// 		},
-// 		{
-// 			name: "Nil values in required fields",
-// 			input: map[string]interface{}{
-// 				"S3": map[string]interface{}{
-// 					"ID":        nil,
-// 					"Feed":      nil,
-// 					"Published": nil,
-// 					"GeoInfo":   nil,
-// 					"Property":  nil,
-// 					"Partner":   nil,
-// 				},
-// 			},
-// 			expectedError: true,
-// 			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
-// 				assert.Empty(t, result)
-// 			},
-// 		},
-// 	}
-
-// 	for _, tt := range tests {
-// 		t.Run(tt.name, func(t *testing.T) {
-// 			var result structs.PropertyDetailsResponse
-// 			err := transformData(tt.input, &result)
-
-// 			if tt.expectedError {
-// 				assert.Error(t, err)
-// 			} else {
-// 				assert.NoError(t, err)
-// 			}
-
-// 			tt.validateResult(t, result)
-// 		})
-// 	}
-// }
+func TestTransformData(t *testing.T) {
+	currentTime := "2025-01-09T06:11:56Z"
+
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		expectedError  bool
+		expectedFields []string
+		validateResult func(*testing.T, structs.PropertyDetailsResponse)
+	}{
+		{
+			name:          "Valid complete data",
+			input:         getMockValidResponse(currentTime),
+			expectedError: false,
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, "TEST123", result.ID)
+				assert.Equal(t, 1, result.Feed)
+				assert.True(t, result.Published)
+
+				// Validate GeoInfo
+				assert.Equal(t, "Test City", result.GeoInfo.City)
+				assert.Equal(t, "Test Country", result.GeoInfo.Country)
+				assert.Len(t, result.GeoInfo.Categories, 1)
+
+				// Validate Property details
+				assert.Equal(t, "Test Property", result.Property.PropertyName)
+				assert.Equal(t, 100, result.Property.Price)
+				assert.Equal(t, 2, result.Property.Counts.Bedroom)
+				assert.Equal(t, 2, result.Property.Counts.Bathroom)
+
+				// Validate Partner details
+				assert.Equal(t, "PARTNER123", result.Partner.ID)
+				assert.Contains(t, result.Partner.Archived, "archived1")
+			},
+		},
+		{
+			name: "Missing S3 data",
+			input: map[string]interface{}{
+				"other": "data",
+			},
+			expectedError:  true,
+			expectedFields: []string{"S3"},
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Empty(t, result)
+			},
+		},
+		{
+			name: "Invalid data types",
+			input: map[string]interface{}{
+				"S3": map[string]interface{}{
+					"ID":        123,    // Invalid: should be string
+					"Feed":      "1",    // Invalid: should be number
+					"Published": "true", // Invalid: should be boolean
+					"GeoInfo": map[string]interface{}{
+						"City":    456,  // Invalid: should be string
+						"Country": true, // Invalid: should be string
+					},
+					"Property": map[string]interface{}{
+						"Price": "invalid", // Invalid: should be number
+					},
+				},
+			},
+			expectedError: true,
+			expectedFields: []string{
+				"S3.ID", "S3.Feed", "S3.Published", "S3.GeoInfo.City", "S3.GeoInfo.Country",
+				"S3.Property.Price", "S3.Property.PropertyName", "S3.Partner",
+			},
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Empty(t, result)
+			},
+		},
+		{
+			name: "Nil values in required fields",
+			input: map[string]interface{}{
+				"S3": map[string]interface{}{
+					"ID":        nil,
+					"Feed":      nil,
+					"Published": nil,
+					"GeoInfo":   nil,
+					"Property":  nil,
+					"Partner":   nil,
+				},
+			},
+			expectedError:  true,
+			expectedFields: []string{"S3.ID", "S3.Feed", "S3.Published", "S3.GeoInfo", "S3.Property", "S3.Partner"},
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Empty(t, result)
+			},
+		},
+		{
+			name: "Optional fields absent",
+			input: map[string]interface{}{
+				"S3": map[string]interface{}{
+					"ID":        "TEST123",
+					"Feed":      float64(1),
+					"Published": true,
+					"GeoInfo":   map[string]interface{}{},
+					"Property": map[string]interface{}{
+						"PropertyName": "Test Property",
+						"Image":        nil,
+					},
+					"Partner": map[string]interface{}{"ID": "PARTNER123"},
+				},
+			},
+			expectedError: false,
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, "TEST123", result.ID)
+				assert.Equal(t, "Test Property", result.Property.PropertyName)
+				assert.Nil(t, result.Property.Image)
+				assert.Equal(t, 0, result.Property.Counts.Bedroom)
+			},
+		},
+		{
+			name: "Fractional counts are truncated",
+			input: func() map[string]interface{} {
+				data := getMockValidResponse(currentTime)
+				property := data["S3"].(map[string]interface{})["Property"].(map[string]interface{})
+				property["Counts"].(map[string]interface{})["Bedroom"] = 2.5
+				return data
+			}(),
+			expectedError: false,
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, 2, result.Property.Counts.Bedroom)
+			},
+		},
+		{
+			name: "Invalid nested values",
+			input: func() map[string]interface{} {
+				data := getMockValidResponse(currentTime)
+				property := data["S3"].(map[string]interface{})["Property"].(map[string]interface{})
+				property["Counts"].(map[string]interface{})["Bedroom"] = "2"
+				property["Amenities"].(map[string]interface{})["1"] = float64(1)
+				return data
+			}(),
+			expectedError:  true,
+			expectedFields: []string{"S3.Property.Amenities.1", "S3.Property.Counts.Bedroom"},
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Empty(t, result)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result structs.PropertyDetailsResponse
+			err := transformData(tt.input, &result)
+
+			if tt.expectedError {
+				var validationErr *ValidationError
+				assert.True(t, errors.As(err, &validationErr))
+				var paths []string
+				for _, field := range validationErr.Fields {
+					paths = append(paths, field.Path)
+				}
+				assert.Equal(t, tt.expectedFields, paths)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tt.validateResult(t, result)
+		})
+	}
+}
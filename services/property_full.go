@@ -0,0 +1,58 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// FetchPropertyFull fetches the upstream document once and builds the S3 details,
+// the OS details and the grouped gallery from it. Only the S3 details are
+// required; an OS block or gallery that cannot be read is reported in Errors
+// and its section left empty.
+func FetchPropertyFull(ctx context.Context, propertyId string, opts FetchOptions) (structs.PropertyFullResponse, FetchMeta, error) {
+	transformedData := structs.PropertyFullResponse{Images: make(structs.ImagesResponse)}
+
+	originalData, meta, err := fetchDocument(ctx, propertyId, opts)
+	if err != nil {
+		return transformedData, meta, err
+	}
+
+	if err := transformData(originalData, &transformedData.Details); err != nil {
+		log.Printf("failed to transform S3 data: %v", err)
+		return transformedData, meta, newPayloadError(propertyId, err)
+	}
+
+	if err := transformOSData(originalData, &transformedData.OSDetails); err != nil {
+		log.Printf("failed to transform OS data: %v", err)
+		transformedData.OSDetails = structs.PropertyDetailsResponse{}
+		addSectionError(&transformedData, "OSDetails", "OS", err)
+	}
+
+	if err := transformImages(originalData, transformedData.Images); err != nil {
+		log.Printf("failed to transform images: %v", err)
+		transformedData.Images = structs.ImagesResponse{}
+		addSectionError(&transformedData, "Images", "S3-Gallery", err)
+	}
+
+	return transformedData, meta, nil
+}
+
+// addSectionError records that section of a full response could not be built
+// from the named upstream block.
+func addSectionError(transformedData *structs.PropertyFullResponse, section, block string, err error) {
+	if transformedData.Errors == nil {
+		transformedData.Errors = map[string]*structs.ErrorResponse{}
+	}
+	sectionErr := &structs.ErrorResponse{
+		Code:    string(ErrorBadUpstreamPayload),
+		Message: fmt.Sprintf("Upstream returned an invalid %s block", block),
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		sectionErr.Fields = validationErr.Fields
+	}
+	transformedData.Errors[section] = sectionErr
+}
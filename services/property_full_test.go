@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"beego-api-service/internal/fakeupstream"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchPropertyFull(t *testing.T) {
+	tests := []struct {
+		name        string
+		propertyID  string
+		expectError bool
+		validate    func(*testing.T, structs.PropertyFullResponse)
+	}{
+		{
+			name:        "Success with all blocks",
+			propertyID:  "TEST-FULL",
+			expectError: false,
+			validate: func(t *testing.T, result structs.PropertyFullResponse) {
+				assert.Equal(t, "TEST123", result.Details.ID)
+				assert.Equal(t, "Test Property", result.Details.Property.PropertyName)
+				assert.Equal(t, "Test Property OS", result.OSDetails.Property.PropertyName)
+				assert.Equal(t, 120, result.OSDetails.Property.Price)
+				assert.Equal(t, structs.ImagesResponse{"kitchen": []string{"http://example.com/k.jpg"}}, result.Images)
+				assert.Nil(t, result.Errors)
+			},
+		},
+		{
+			name:        "Missing OS block and gallery",
+			propertyID:  "TEST-S3",
+			expectError: false,
+			validate: func(t *testing.T, result structs.PropertyFullResponse) {
+				assert.Equal(t, "Test Property", result.Details.Property.PropertyName)
+				assert.Equal(t, structs.PropertyDetailsResponse{}, result.OSDetails)
+				assert.Empty(t, result.Images)
+				assert.Equal(t, map[string]*structs.ErrorResponse{
+					"OSDetails": {Code: "bad_upstream_payload", Message: "Upstream returned an invalid OS block"},
+					"Images":    {Code: "bad_upstream_payload", Message: "Upstream returned an invalid S3-Gallery block"},
+				}, result.Errors)
+			},
+		},
+		{
+			name:        "Invalid gallery image",
+			propertyID:  "TEST-BAD-GALLERY",
+			expectError: false,
+			validate: func(t *testing.T, result structs.PropertyFullResponse) {
+				assert.Equal(t, "TEST123", result.OSDetails.ID)
+				assert.Len(t, result.Errors, 1)
+				assert.Equal(t, []structs.FieldError{{Path: "S3-Gallery.category1[0].label", Reason: "must be a string, got number"}}, result.Errors["Images"].Fields)
+			},
+		},
+		{
+			name:        "Invalid S3 block",
+			propertyID:  "TEST-BAD-S3",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, url := startFakeUpstream(t, fakeupstream.Scenario{})
+			web.AppConfig.Set("externalAPIBaseURL", url)
+
+			result, _, err := FetchPropertyFull(context.Background(), tt.propertyID, FetchOptions{})
+
+			assert.Equal(t, 1, upstream.Requests())
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, result)
+		})
+	}
+}
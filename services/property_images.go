@@ -2,57 +2,49 @@ package services
 
 import (
 	"beego-api-service/structs"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
 	"log"
-	"net/http"
-
-	"github.com/beego/beego/v2/server/web"
 )
 
-func FetchPropertyImages(propertyId string) (structs.ImagesResponse, error) {
+func FetchPropertyImages(ctx context.Context, propertyId string, opts FetchOptions) (structs.ImagesResponse, FetchMeta, error) {
 	transformedData := make(structs.ImagesResponse)
 
-	// Load the external API base URL from the configuration
-	externalAPIBaseURL, err := web.AppConfig.String("externalAPIBaseURL")
+	// Fetch and decode the upstream property document
+	originalData, meta, err := fetchDocument(ctx, propertyId, opts)
 	if err != nil {
-		log.Printf("failed to load external API URL from config: %v", err)
-		return transformedData, err
+		return transformedData, meta, err
 	}
 
-	// Construct the external API URL
-	externalAPIURL := fmt.Sprintf("%s?propertyId=%s&languageCode=en", externalAPIBaseURL, propertyId)
-	resp, err := http.Get(externalAPIURL)
-	if err != nil {
-		log.Printf("HTTP request failed: %v", err)
-		return transformedData, err
+	// Transform the gallery data
+	if err := transformImages(originalData, transformedData); err != nil {
+		log.Printf("failed to transform images: %v", err)
+		return transformedData, meta, newPayloadError(propertyId, err)
 	}
-	defer resp.Body.Close()
 
-	// Decode the JSON response
-	var originalData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&originalData); err != nil {
-		log.Printf("failed to decode response: %v", err)
-		return transformedData, err
-	}
+	return transformedData, meta, nil
+}
 
+// transformImages groups the confident images of the S3-Gallery block by
+// label. Images that are not objects with a string label and url are
+// reported in a *ValidationError.
+func transformImages(originalData map[string]interface{}, transformedData structs.ImagesResponse) error {
 	// Extract gallery data
 	galleryData, ok := originalData["S3-Gallery"].(map[string]interface{})
 	if !ok {
 		log.Printf("invalid S3-Gallery format: expected map[string]interface{}, got %T", originalData["S3-Gallery"])
-		return transformedData, errors.New("invalid S3-Gallery format")
+		return errors.New("invalid S3-Gallery format")
 	}
 
-	// Transform the gallery data
-	for _, images := range galleryData {
-		for _, image := range images.([]interface{}) {
-			img := image.(map[string]interface{})
-			label := img["label"].(string)
-			url := img["url"].(string)
+	var d documentDecoder
+	gallery := docNode{path: "S3-Gallery", fields: galleryData}
+	for _, group := range sortedKeys(galleryData) {
+		for _, img := range d.Objects(gallery, group, required) {
+			label := d.String(img, "label", required)
+			url := d.String(img, "url", required)
 
 			// Extract confidence value
-			confidence, ok := img["confidence"].(float64)
+			confidence, ok := img.fields["confidence"].(float64)
 			if !ok {
 				// If confidence field doesn't exist or is not a number, skip this image
 				log.Printf("invalid or missing confidence value for image: %s", url)
@@ -69,5 +61,5 @@ func FetchPropertyImages(propertyId string) (structs.ImagesResponse, error) {
 		}
 	}
 
-	return transformedData, nil
+	return d.Err()
 }
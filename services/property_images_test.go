@@ -1,9 +1,9 @@
 package services
 
 import (
+	"beego-api-service/internal/fakeupstream"
 	"beego-api-service/structs"
-	"net/http"
-	"net/http/httptest"
+	"context"
 	"testing"
 
 	"github.com/beego/beego/v2/server/web"
@@ -15,34 +15,13 @@ func TestFetchPropertyImages(t *testing.T) {
 	tests := []struct {
 		name           string
 		propertyID     string
-		mockResponse   string
+		scenario       fakeupstream.Scenario
 		expectedImages structs.ImagesResponse
 		expectError    bool
 	}{
 		{
 			name:       "Success with valid images and confidence",
-			propertyID: "123",
-			mockResponse: `{
-                "S3-Gallery": {
-                    "category1": [
-                        {
-                            "label": "bedroom",
-                            "url": "http://example.com/image1.jpg",
-                            "confidence": 98.5
-                        },
-                        {
-                            "label": "bedroom",
-                            "url": "http://example.com/image2.jpg",
-                            "confidence": 85.5
-                        },
-                        {
-                            "label": "kitchen",
-                            "url": "http://example.com/image3.jpg",
-                            "confidence": 97.0
-                        }
-                    ]
-                }
-            }`,
+			propertyID: "TEST-GALLERY",
 			expectedImages: structs.ImagesResponse{
 				"bedroom": []string{"http://example.com/image1.jpg"},
 				"kitchen": []string{"http://example.com/image3.jpg"},
@@ -50,44 +29,26 @@ func TestFetchPropertyImages(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name:       "Skip images with low confidence",
-			propertyID: "124",
-			mockResponse: `{
-                "S3-Gallery": {
-                    "category1": [
-                        {
-                            "label": "bedroom",
-                            "url": "http://example.com/image1.jpg",
-                            "confidence": 90.0
-                        }
-                    ]
-                }
-            }`,
+			name:           "Skip images with low confidence",
+			propertyID:     "TEST-GALLERY-LOW",
 			expectedImages: structs.ImagesResponse{},
 			expectError:    false,
 		},
 		{
-			name:       "Invalid JSON response",
-			propertyID: "125",
-			mockResponse: `{
-                "S3-Gallery": invalid
-            }`,
+			name:           "Invalid JSON response",
+			propertyID:     "TEST-GALLERY",
+			scenario:       fakeupstream.Scenario{TruncateRate: 1},
 			expectedImages: structs.ImagesResponse{},
 			expectError:    true,
 		},
 		{
-			name:       "Missing confidence value",
-			propertyID: "126",
-			mockResponse: `{
-                "S3-Gallery": {
-                    "category1": [
-                        {
-                            "label": "bedroom",
-                            "url": "http://example.com/image1.jpg"
-                        }
-                    ]
-                }
-            }`,
+			name:        "Images that are not objects with a string label and url",
+			propertyID:  "TEST-GALLERY-INVALID",
+			expectError: true,
+		},
+		{
+			name:           "Missing confidence value",
+			propertyID:     "TEST-GALLERY-NO-CONFIDENCE",
 			expectedImages: structs.ImagesResponse{},
 			expectError:    false,
 		},
@@ -95,18 +56,12 @@ func TestFetchPropertyImages(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Create a test server
-			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json")
-				w.Write([]byte(tt.mockResponse))
-			}))
-			defer server.Close()
-
-			// Set mock configuration
-			web.AppConfig.Set("externalAPIBaseURL", server.URL)
+			// Serve the gallery fixtures
+			_, url := startFakeUpstream(t, tt.scenario)
+			web.AppConfig.Set("externalAPIBaseURL", url)
 
 			// Call the function
-			result, err := FetchPropertyImages(tt.propertyID)
+			result, _, err := FetchPropertyImages(context.Background(), tt.propertyID, FetchOptions{})
 
 			// Assert results
 			if tt.expectError {
@@ -123,7 +78,7 @@ func TestFetchPropertyImagesHTTPError(t *testing.T) {
 	// Test case for HTTP request failure
 	web.AppConfig.Set("externalAPIBaseURL", "http://invalid-url")
 
-	result, err := FetchPropertyImages("123")
+	result, _, err := FetchPropertyImages(context.Background(), "123", FetchOptions{})
 
 	assert.Error(t, err)
 	assert.Empty(t, result)
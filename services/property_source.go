@@ -0,0 +1,198 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Source selects which block of the upstream document a property is built
+// from.
+type Source string
+
+const (
+	SourceS3     Source = "s3"
+	SourceOS     Source = "os"
+	SourceMerged Source = "merged"
+	// SourceNewer is only used in merge rules: it prefers whichever block has
+	// the later Property.UpdatedAt.
+	SourceNewer Source = "newer"
+)
+
+// ParseSource validates a requested source. An empty value selects
+// defaultSource.
+func ParseSource(value string, defaultSource Source) (Source, error) {
+	switch source := Source(strings.ToLower(strings.TrimSpace(value))); source {
+	case "":
+		return defaultSource, nil
+	case SourceS3, SourceOS, SourceMerged:
+		return source, nil
+	}
+	return "", fmt.Errorf("unsupported source: %s", value)
+}
+
+// transformSource builds transformedData from the blocks of originalData
+// selected by source. When provenance is not nil it is filled with where each
+// field came from.
+func transformSource(originalData map[string]interface{}, source Source, transformedData *structs.PropertyDetailsResponse, provenance structs.Provenance) error {
+	var err error
+	switch source {
+	case SourceS3:
+		err = transformData(originalData, transformedData)
+	case SourceOS:
+		err = transformOSData(originalData, transformedData)
+	case SourceMerged:
+		return transformMerged(originalData, loadMergeRules(), transformedData, provenance)
+	default:
+		return fmt.Errorf("unsupported source: %s", source)
+	}
+	if err == nil && provenance != nil {
+		recordProvenance(originalData, *transformedData, source, provenance)
+	}
+	return err
+}
+
+// transformMerged combines the S3 and OS blocks field by field according to
+// rules. A block that is missing or invalid is skipped as long as the other
+// one can be used.
+func transformMerged(originalData map[string]interface{}, rules mergeRules, transformedData *structs.PropertyDetailsResponse, provenance structs.Provenance) error {
+	var s3Details, osDetails structs.PropertyDetailsResponse
+	s3Err := transformData(originalData, &s3Details)
+	osErr := transformOSData(originalData, &osDetails)
+
+	switch {
+	case s3Err != nil && osErr != nil:
+		return s3Err
+	case s3Err != nil:
+		log.Printf("merging without S3 data: %v", s3Err)
+		*transformedData = osDetails
+		if provenance != nil {
+			recordProvenance(originalData, osDetails, SourceOS, provenance)
+		}
+		return nil
+	case osErr != nil:
+		log.Printf("merging without OS data: %v", osErr)
+		*transformedData = s3Details
+		if provenance != nil {
+			recordProvenance(originalData, s3Details, SourceS3, provenance)
+		}
+		return nil
+	}
+
+	m := merger{rules: rules, newer: newerSource(s3Details, osDetails), originalData: originalData, provenance: provenance}
+	m.mergeValue(reflect.ValueOf(transformedData).Elem(), reflect.ValueOf(s3Details), reflect.ValueOf(osDetails), "")
+	return nil
+}
+
+// mergeRules decides which block wins for each field. Fields rules holds
+// per-path overrides; the longest matching path prefix applies, and Default
+// covers everything else.
+type mergeRules struct {
+	Default Source
+	Fields  map[string]Source
+}
+
+// loadMergeRules reads mergePrecedence (s3, os or newer) and
+// mergeFieldPrecedence, e.g. "Property.Price:os;Partner:s3".
+func loadMergeRules() mergeRules {
+	rules := mergeRules{
+		Default: parseMergeSource(web.AppConfig.DefaultString("mergePrecedence", string(SourceNewer)), SourceNewer),
+		Fields:  make(map[string]Source),
+	}
+	for _, entry := range web.AppConfig.DefaultStrings("mergeFieldPrecedence", []string{"Property.Price:os"}) {
+		path, source, ok := strings.Cut(entry, ":")
+		path = strings.TrimSpace(path)
+		if !ok || path == "" {
+			continue
+		}
+		rules.Fields[path] = parseMergeSource(source, rules.Default)
+	}
+	return rules
+}
+
+func parseMergeSource(value string, fallback Source) Source {
+	switch source := Source(strings.ToLower(strings.TrimSpace(value))); source {
+	case SourceS3, SourceOS, SourceNewer:
+		return source
+	}
+	log.Printf("ignoring unknown merge precedence %q", value)
+	return fallback
+}
+
+// sourceFor returns the block preferred for path.
+func (r mergeRules) sourceFor(path string, newer Source) Source {
+	source, _ := r.fieldRule(path)
+	if source == SourceNewer {
+		return newer
+	}
+	return source
+}
+
+// fieldRule returns the rule for path and whether it came from a per-field
+// override rather than the default.
+func (r mergeRules) fieldRule(path string) (Source, bool) {
+	source, matched := r.Default, -1
+	for prefix, s := range r.Fields {
+		if len(prefix) > matched && (path == prefix || strings.HasPrefix(path, prefix+".")) {
+			source, matched = s, len(prefix)
+		}
+	}
+	return source, matched >= 0
+}
+
+// newerSource returns the block with the later Property.UpdatedAt. S3 wins
+// ties and timestamps that cannot be compared.
+func newerSource(s3Details, osDetails structs.PropertyDetailsResponse) Source {
+	s3Time, s3Err := time.Parse(time.RFC3339, s3Details.Property.UpdatedAt)
+	osTime, osErr := time.Parse(time.RFC3339, osDetails.Property.UpdatedAt)
+	switch {
+	case osErr != nil:
+		return SourceS3
+	case s3Err != nil || osTime.After(s3Time):
+		return SourceOS
+	}
+	return SourceS3
+}
+
+// merger combines the S3 and OS views of one property.
+type merger struct {
+	rules        mergeRules
+	newer        Source
+	originalData map[string]interface{}
+	provenance   structs.Provenance
+}
+
+// mergeValue walks the response struct and copies every leaf field from the
+// preferred block, falling back to the other block when the preferred block
+// does not carry the field. A value the preferred block does carry is kept
+// even when it is false or 0.
+func (m merger) mergeValue(dst, s3Value, osValue reflect.Value, path string) {
+	if dst.Kind() == reflect.Struct {
+		for i := 0; i < dst.NumField(); i++ {
+			m.mergeValue(dst.Field(i), s3Value.Field(i), osValue.Field(i), joinPath(path, dst.Type().Field(i).Name))
+		}
+		return
+	}
+
+	source, other := SourceS3, SourceOS
+	preferred, fallback := s3Value, osValue
+	if m.rules.sourceFor(path, m.newer) == SourceOS {
+		source, other = SourceOS, SourceS3
+		preferred, fallback = osValue, s3Value
+	}
+	if key := upstreamKey(source, path); key == "" || !documentHas(m.originalData, key) {
+		source, preferred = other, fallback
+	}
+	dst.Set(preferred)
+
+	if m.provenance != nil {
+		provenance := fieldProvenance(m.originalData, source, path)
+		_, provenance.Override = m.rules.fieldRule(path)
+		m.provenance[path] = provenance
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"testing"
+
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// getMockMergeDocument has an S3 and an OS block that disagree. With the
+// dates below it is the document of the TEST-MERGE fixture.
+func getMockMergeDocument(s3UpdatedAt, osUpdatedAt string) map[string]interface{} {
+	document := getMockValidResponse(s3UpdatedAt)
+	document["OS"] = map[string]interface{}{
+		"id":            "TEST123",
+		"property_name": "OS Property",
+		"usd_price":     float64(120),
+		"city":          "OS City",
+		"updated_at":    osUpdatedAt,
+	}
+	return document
+}
+
+func TestParseSource(t *testing.T) {
+	source, err := ParseSource("", SourceS3)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceS3, source)
+
+	source, err = ParseSource(" OS ", SourceS3)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceOS, source)
+
+	_, err = ParseSource("newer", SourceS3)
+	assert.Error(t, err)
+}
+
+func TestMergeRulesSourceFor(t *testing.T) {
+	rules := mergeRules{
+		Default: SourceNewer,
+		Fields: map[string]Source{
+			"Property":       SourceS3,
+			"Property.Price": SourceOS,
+		},
+	}
+
+	assert.Equal(t, SourceOS, rules.sourceFor("Property.Price", SourceS3))
+	assert.Equal(t, SourceS3, rules.sourceFor("Property.PropertyName", SourceOS))
+	assert.Equal(t, SourceS3, rules.sourceFor("Property.Counts.Bedroom", SourceOS))
+	assert.Equal(t, SourceOS, rules.sourceFor("GeoInfo.City", SourceOS))
+	assert.Equal(t, SourceS3, rules.sourceFor("PropertyX", SourceS3))
+}
+
+func TestLoadMergeRules(t *testing.T) {
+	web.AppConfig.Set("mergePrecedence", "s3")
+	web.AppConfig.Set("mergeFieldPrecedence", "Property.Price:os;Partner:newer;bogus")
+	defer web.AppConfig.Set("mergePrecedence", "")
+	defer web.AppConfig.Set("mergeFieldPrecedence", "Property.Price:os")
+
+	rules := loadMergeRules()
+
+	assert.Equal(t, SourceS3, rules.Default)
+	assert.Equal(t, map[string]Source{"Property.Price": SourceOS, "Partner": SourceNewer}, rules.Fields)
+}
+
+func TestTransformMerged(t *testing.T) {
+	rules := mergeRules{Default: SourceNewer, Fields: map[string]Source{"Property.Price": SourceOS}}
+
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		expectedError  bool
+		validateResult func(*testing.T, structs.PropertyDetailsResponse)
+	}{
+		{
+			name:  "S3 is newer",
+			input: getMockMergeDocument("2025-01-09T06:11:56Z", "2024-05-03T11:46:19.189256+00:00"),
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, "Test Property", result.Property.PropertyName)
+				assert.Equal(t, "Test City", result.GeoInfo.City)
+				assert.Equal(t, 120, result.Property.Price)
+			},
+		},
+		{
+			name:  "OS is newer",
+			input: getMockMergeDocument("2024-05-03T11:46:19Z", "2025-01-09T06:11:56.5+00:00"),
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, "OS Property", result.Property.PropertyName)
+				assert.Equal(t, "OS City", result.GeoInfo.City)
+				// Fields the OS block lacks are filled from S3
+				assert.Equal(t, 2, result.Property.Counts.Bedroom)
+				assert.Equal(t, "owner123", result.Partner.OwnerID)
+			},
+		},
+		{
+			name: "False and zero values of the preferred block are kept",
+			input: func() map[string]interface{} {
+				document := getMockMergeDocument("2024-05-03T11:46:19Z", "2025-01-09T06:11:56.5+00:00")
+				os := document["OS"].(map[string]interface{})
+				os["published"] = false
+				os["usd_price"] = float64(0)
+				os["property_flags"] = map[string]interface{}{"eco_friendly": false}
+				return document
+			}(),
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.False(t, result.Published)
+				assert.Equal(t, 0, result.Property.Price)
+				assert.False(t, result.Property.EcoFriendly)
+				// Fields the OS block lacks are still filled from S3
+				assert.Equal(t, 2, result.Property.Counts.Bedroom)
+			},
+		},
+		{
+			name: "Missing OS block",
+			input: func() map[string]interface{} {
+				document := getMockMergeDocument("2025-01-09T06:11:56Z", "")
+				delete(document, "OS")
+				return document
+			}(),
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Equal(t, "Test Property", result.Property.PropertyName)
+				assert.Equal(t, 100, result.Property.Price)
+			},
+		},
+		{
+			name:          "No usable block",
+			input:         map[string]interface{}{"S3": "invalid"},
+			expectedError: true,
+			validateResult: func(t *testing.T, result structs.PropertyDetailsResponse) {
+				assert.Empty(t, result)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result structs.PropertyDetailsResponse
+			err := transformMerged(tt.input, rules, &result, nil)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tt.validateResult(t, result)
+		})
+	}
+}
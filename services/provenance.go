@@ -0,0 +1,106 @@
+package services
+
+import (
+	"beego-api-service/structs"
+	"reflect"
+	"strings"
+)
+
+// ProvenanceDefault marks fields that have no upstream value.
+const ProvenanceDefault = "default"
+
+// osFieldKeys maps response fields to the OS block keys transformOSData reads
+// them from. S3 keys mirror the response field paths.
+var osFieldKeys = map[string]string{
+	"ID":                              "id",
+	"Feed":                            "feed",
+	"Published":                       "published",
+	"GeoInfo.Categories":              "categories",
+	"GeoInfo.City":                    "city",
+	"GeoInfo.Country":                 "country",
+	"GeoInfo.CountryCode":             "country_code",
+	"GeoInfo.Display":                 "display",
+	"GeoInfo.LocationID":              "location_id",
+	"GeoInfo.StateAbbr":               "state_abbr",
+	"GeoInfo.Lat":                     "lonlat.coordinates",
+	"GeoInfo.Lng":                     "lonlat.coordinates",
+	"Property.Amenities":              "amenity_categories",
+	"Property.Counts.Bedroom":         "bedroom_count",
+	"Property.Counts.Bathroom":        "bathroom_count",
+	"Property.Counts.Reviews":         "number_of_review",
+	"Property.Counts.Occupancy":       "occupancy",
+	"Property.EcoFriendly":            "property_flags.eco_friendly",
+	"Property.FeatureImage":           "feature_image",
+	"Property.Price":                  "usd_price",
+	"Property.PropertyName":           "property_name",
+	"Property.PropertySlug":           "property_slug",
+	"Property.PropertyType":           "property_type",
+	"Property.PropertyTypeCategoryId": "property_type_category",
+	"Property.ReviewScore":            "review_score_general",
+	"Property.RoomSize":               "room_size_sqft",
+	"Property.MinStay":                "min_stay",
+	"Property.UpdatedAt":              "updated_at",
+	"Partner.ID":                      "id",
+	"Partner.Archived":                "archived",
+	"Partner.OwnerID":                 "owner_id",
+	"Partner.HcomID":                  "hcom_id",
+	"Partner.BrandId":                 "brand_id",
+	"Partner.URL":                     "feed_provider_url",
+	"Partner.UnitNumber":              "unit_number",
+	"Partner.EpCluster":               "cluster_id",
+}
+
+// upstreamKey returns the document key a field is read from in source, or ""
+// if that block does not carry the field.
+func upstreamKey(source Source, path string) string {
+	switch source {
+	case SourceS3:
+		return "S3." + path
+	case SourceOS:
+		if key, ok := osFieldKeys[path]; ok {
+			return "OS." + key
+		}
+	}
+	return ""
+}
+
+// fieldProvenance reports a field taken from source. Fields the upstream
+// document does not carry are reported as defaults.
+func fieldProvenance(originalData map[string]interface{}, source Source, path string) structs.FieldProvenance {
+	key := upstreamKey(source, path)
+	if key == "" || !documentHas(originalData, key) {
+		return structs.FieldProvenance{Source: ProvenanceDefault, Key: key}
+	}
+	return structs.FieldProvenance{Source: strings.ToUpper(string(source)), Key: key}
+}
+
+// recordProvenance fills provenance for details built from a single block.
+func recordProvenance(originalData map[string]interface{}, details structs.PropertyDetailsResponse, source Source, provenance structs.Provenance) {
+	walkLeaves(reflect.ValueOf(details), "", func(path string) {
+		provenance[path] = fieldProvenance(originalData, source, path)
+	})
+}
+
+// walkLeaves calls visit with the path of every non-struct field of value.
+func walkLeaves(value reflect.Value, path string, visit func(path string)) {
+	if value.Kind() != reflect.Struct {
+		visit(path)
+		return
+	}
+	for i := 0; i < value.NumField(); i++ {
+		walkLeaves(value.Field(i), joinPath(path, value.Type().Field(i).Name), visit)
+	}
+}
+
+// documentHas reports whether the dotted key holds a non-null value.
+func documentHas(document map[string]interface{}, key string) bool {
+	var value interface{} = document
+	for _, part := range strings.Split(key, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		value = object[part]
+	}
+	return value != nil
+}
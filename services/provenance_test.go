@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"beego-api-service/internal/fakeupstream"
+	"beego-api-service/structs"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformSourceProvenance(t *testing.T) {
+	rules := mergeRules{Default: SourceS3, Fields: map[string]Source{"Property.Price": SourceOS}}
+	document := getMockMergeDocument("2025-01-09T06:11:56Z", "2024-05-03T11:46:19Z")
+
+	tests := []struct {
+		name     string
+		source   Source
+		expected map[string]structs.FieldProvenance
+	}{
+		{
+			name:   "S3",
+			source: SourceS3,
+			expected: map[string]structs.FieldProvenance{
+				"Property.Price":        {Source: "S3", Key: "S3.Property.Price"},
+				"Property.PropertyName": {Source: "S3", Key: "S3.Property.PropertyName"},
+				"Property.Image":        {Source: "S3", Key: "S3.Property.Image"},
+			},
+		},
+		{
+			name:   "OS",
+			source: SourceOS,
+			expected: map[string]structs.FieldProvenance{
+				"Property.Price":          {Source: "OS", Key: "OS.usd_price"},
+				"Property.PropertyName":   {Source: "OS", Key: "OS.property_name"},
+				"Property.Counts.Bedroom": {Source: ProvenanceDefault, Key: "OS.bedroom_count"},
+				"Property.Image":          {Source: ProvenanceDefault},
+			},
+		},
+		{
+			name:   "Merged",
+			source: SourceMerged,
+			expected: map[string]structs.FieldProvenance{
+				"Property.Price":          {Source: "OS", Key: "OS.usd_price", Override: true},
+				"Property.PropertyName":   {Source: "S3", Key: "S3.Property.PropertyName"},
+				"Property.Counts.Bedroom": {Source: "S3", Key: "S3.Property.Counts.Bedroom"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result structs.PropertyDetailsResponse
+			provenance := structs.Provenance{}
+			var err error
+			if tt.source == SourceMerged {
+				err = transformMerged(document, rules, &result, provenance)
+			} else {
+				err = transformSource(document, tt.source, &result, provenance)
+			}
+
+			assert.NoError(t, err)
+			for path, expected := range tt.expected {
+				assert.Equal(t, expected, provenance[path], path)
+			}
+		})
+	}
+}
+
+func TestMergedProvenanceFallsBack(t *testing.T) {
+	rules := mergeRules{Default: SourceOS}
+	document := getMockMergeDocument("2025-01-09T06:11:56Z", "2024-05-03T11:46:19Z")
+
+	var result structs.PropertyDetailsResponse
+	provenance := structs.Provenance{}
+	assert.NoError(t, transformMerged(document, rules, &result, provenance))
+
+	// The OS block has no bedroom count, so the S3 value is used
+	assert.Equal(t, structs.FieldProvenance{Source: "S3", Key: "S3.Property.Counts.Bedroom"}, provenance["Property.Counts.Bedroom"])
+	assert.Equal(t, structs.FieldProvenance{Source: "OS", Key: "OS.city"}, provenance["GeoInfo.City"])
+}
+
+func TestFetchPropertyDetailsWithProvenance(t *testing.T) {
+	_, url := startFakeUpstream(t, fakeupstream.Scenario{})
+	web.AppConfig.Set("externalAPIBaseURL", url)
+
+	result, _, err := FetchPropertyDetailsWithProvenance(context.Background(), "TEST-MERGE", FetchOptions{Source: SourceOS})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "OS Property", result.Details.Property.PropertyName)
+	assert.Equal(t, structs.FieldProvenance{Source: "OS", Key: "OS.usd_price"}, result.Provenance["Property.Price"])
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPolicy controls how temporary upstream failures are retried.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	// deadline bounds the total time spent on one request including retries.
+	deadline time.Duration
+}
+
+// backoff returns how long to wait after the given failed attempt. The delay
+// grows exponentially with full jitter, but never undercuts the upstream's
+// Retry-After.
+func (p *retryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	ceiling := p.baseDelay << uint(attempt-1)
+	if ceiling <= 0 || (p.maxDelay > 0 && ceiling > p.maxDelay) {
+		ceiling = p.maxDelay
+	}
+
+	var delay time.Duration
+	if ceiling > 0 {
+		delay = time.Duration(rand.Int63n(int64(ceiling) + 1))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date. Missing or malformed values yield 0.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
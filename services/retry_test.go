@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &retryPolicy{maxAttempts: 5, baseDelay: 100 * time.Millisecond, maxDelay: 300 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		min        time.Duration
+		max        time.Duration
+	}{
+		{name: "First retry", attempt: 1, min: 0, max: 100 * time.Millisecond},
+		{name: "Second retry", attempt: 2, min: 0, max: 200 * time.Millisecond},
+		{name: "Capped by max delay", attempt: 4, min: 0, max: 300 * time.Millisecond},
+		{name: "Honors Retry-After", attempt: 1, retryAfter: 2 * time.Second, min: 2 * time.Second, max: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				delay := policy.backoff(tt.attempt, tt.retryAfter)
+				assert.GreaterOrEqual(t, delay, tt.min)
+				assert.LessOrEqual(t, delay, tt.max)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 9, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "Seconds", value: "3", expected: 3 * time.Second},
+		{name: "HTTP date", value: "Thu, 09 Jan 2025 08:00:10 GMT", expected: 10 * time.Second},
+		{name: "Date in the past", value: "Thu, 09 Jan 2025 07:59:00 GMT", expected: 0},
+		{name: "Missing", value: "", expected: 0},
+		{name: "Malformed", value: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseRetryAfter(tt.value, now))
+		})
+	}
+}
+
+func TestUpstreamClientRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		failureStatus    int
+		retryAfter       int
+		expectedAttempts int
+		expectError      bool
+	}{
+		{
+			name:             "Recovers after transient failures",
+			failures:         2,
+			failureStatus:    http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+			expectError:      false,
+		},
+		{
+			name:             "Gives up after max attempts",
+			failures:         5,
+			failureStatus:    http.StatusBadGateway,
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+		{
+			name:             "Does not retry not found",
+			failures:         1,
+			failureStatus:    http.StatusNotFound,
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+		{
+			name:             "Retry-After beyond the deadline",
+			failures:         1,
+			failureStatus:    http.StatusTooManyRequests,
+			retryAfter:       10,
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, url := startFakeUpstream(t, fakeupstream.Scenario{
+				FailFirst:         tt.failures,
+				ErrorStatus:       tt.failureStatus,
+				RetryAfterSeconds: tt.retryAfter,
+			})
+
+			client := NewUpstreamClient(url, time.Second)
+			client.retry = &retryPolicy{
+				maxAttempts: 3,
+				baseDelay:   time.Millisecond,
+				maxDelay:    5 * time.Millisecond,
+				deadline:    time.Second,
+			}
+
+			result, meta, err := client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+
+			assert.Equal(t, tt.expectedAttempts, meta.Attempts)
+			assert.Equal(t, tt.expectedAttempts, upstream.Requests())
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, result, "S3")
+			}
+		})
+	}
+}
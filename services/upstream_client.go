@@ -0,0 +1,526 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+const defaultUpstreamTimeout = 10 * time.Second
+
+// UpstreamErrorKind classifies why a call to the external property API failed.
+type UpstreamErrorKind string
+
+const (
+	UpstreamErrorConfig    UpstreamErrorKind = "config"
+	UpstreamErrorTransport UpstreamErrorKind = "transport"
+	UpstreamErrorTimeout   UpstreamErrorKind = "timeout"
+	UpstreamErrorStatus    UpstreamErrorKind = "status"
+	UpstreamErrorDecode    UpstreamErrorKind = "decode"
+	// UpstreamErrorCanceled means the caller went away before the upstream answered.
+	UpstreamErrorCanceled UpstreamErrorKind = "canceled"
+	// UpstreamErrorCircuitOpen means the request was rejected without calling
+	// the upstream because the circuit breaker is open.
+	UpstreamErrorCircuitOpen UpstreamErrorKind = "circuit_open"
+)
+
+// UpstreamError is returned by UpstreamClient for every failed upstream call.
+type UpstreamError struct {
+	Kind       UpstreamErrorKind
+	StatusCode int
+	URL        string
+	Err        error
+	// RetryAfter is set for UpstreamErrorCircuitOpen and for upstream
+	// responses carrying a Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Kind == UpstreamErrorCircuitOpen {
+		return fmt.Sprintf("upstream circuit breaker is open, retry after %s", e.RetryAfter)
+	}
+	if e.Kind == UpstreamErrorStatus {
+		return fmt.Sprintf("upstream %s: unexpected status %d", e.URL, e.StatusCode)
+	}
+	if e.URL == "" {
+		return fmt.Sprintf("upstream %s error: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("upstream %s error for %s: %v", e.Kind, e.URL, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the same request may succeed if tried again.
+func (e *UpstreamError) Temporary() bool {
+	switch e.Kind {
+	case UpstreamErrorTransport, UpstreamErrorTimeout:
+		return true
+	case UpstreamErrorStatus:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// UpstreamClient is the single entry point to the external dynamodb-s3-os API.
+// It owns the base URL, the HTTP client and the headers sent with every request.
+type UpstreamClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Headers    http.Header
+
+	cache     *documentCache
+	inflight  inflightGroup
+	breaker   *circuitBreaker
+	retry     *retryPolicy
+	endpoints *endpointPool
+	mode      UpstreamMode
+	fixtures  *fixtureStore
+	configKey string
+}
+
+func NewUpstreamClient(baseURL string, timeout time.Duration) *UpstreamClient {
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+	headers.Set("User-Agent", "beego-api-service")
+
+	return &UpstreamClient{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Timeout: timeout},
+		Headers:    headers,
+	}
+}
+
+var (
+	defaultClientMu sync.Mutex
+	defaultClient   *UpstreamClient
+)
+
+// DefaultUpstreamClient returns the shared client built from app.conf.
+// The client is rebuilt whenever the configured upstream URLs change.
+func DefaultUpstreamClient() (*UpstreamClient, error) {
+	mode := parseUpstreamMode(web.AppConfig.DefaultString("upstreamMode", string(UpstreamModePassthrough)))
+	fixturesDir := web.AppConfig.DefaultString("upstreamFixturesDir", "fixtures")
+
+	baseURLs := upstreamBaseURLs()
+	if len(baseURLs) == 0 && mode == UpstreamModeReplay {
+		// Replay never calls the upstream, so no URL is needed
+		baseURLs = []string{"file://" + fixturesDir}
+	}
+	if len(baseURLs) == 0 {
+		return nil, &UpstreamError{Kind: UpstreamErrorConfig, Err: errors.New("externalAPIBaseURL is not configured")}
+	}
+	configKey := strings.Join(append([]string{string(mode), fixturesDir}, baseURLs...), ";")
+
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+
+	if defaultClient == nil || defaultClient.configKey != configKey {
+		defaultClient = newUpstreamClientFromConfig(baseURLs)
+		defaultClient.mode = mode
+		if mode != UpstreamModePassthrough {
+			defaultClient.fixtures = &fixtureStore{dir: fixturesDir}
+		}
+		defaultClient.configKey = configKey
+	}
+	return defaultClient, nil
+}
+
+// upstreamBaseURLs reads the replicas of the external API from
+// externalAPIBaseURLs, falling back to the single externalAPIBaseURL.
+func upstreamBaseURLs() []string {
+	var baseURLs []string
+	for _, baseURL := range web.AppConfig.DefaultStrings("externalAPIBaseURLs", nil) {
+		if baseURL = strings.TrimSpace(baseURL); baseURL != "" {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+	if len(baseURLs) == 0 {
+		if baseURL := strings.TrimSpace(web.AppConfig.DefaultString("externalAPIBaseURL", "")); baseURL != "" {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+	return baseURLs
+}
+
+func newUpstreamClientFromConfig(baseURLs []string) *UpstreamClient {
+	timeout := time.Duration(web.AppConfig.DefaultInt("upstreamTimeoutMs", int(defaultUpstreamTimeout/time.Millisecond))) * time.Millisecond
+	client := NewUpstreamClient(baseURLs[0], timeout)
+
+	if token := web.AppConfig.DefaultString("upstreamAuthToken", ""); token != "" {
+		client.Headers.Set("Authorization", "Bearer "+token)
+	}
+
+	// upstreamHeaders = "X-Header-One: value;X-Header-Two: value"
+	for _, header := range web.AppConfig.DefaultStrings("upstreamHeaders", nil) {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			log.Printf("ignoring malformed upstream header %q", header)
+			continue
+		}
+		client.Headers.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if web.AppConfig.DefaultBool("circuitBreakerEnabled", true) {
+		client.breaker = newCircuitBreaker(
+			web.AppConfig.DefaultFloat("circuitBreakerFailureRate", 0.5),
+			web.AppConfig.DefaultInt("circuitBreakerMinRequests", 20),
+			time.Duration(web.AppConfig.DefaultInt("circuitBreakerWindowSeconds", 60))*time.Second,
+			time.Duration(web.AppConfig.DefaultInt("circuitBreakerCoolDownSeconds", 30))*time.Second,
+			web.AppConfig.DefaultInt("circuitBreakerHalfOpenRequests", 1),
+		)
+	}
+
+	client.retry = &retryPolicy{
+		maxAttempts: web.AppConfig.DefaultInt("upstreamRetryMaxAttempts", 3),
+		baseDelay:   time.Duration(web.AppConfig.DefaultInt("upstreamRetryBaseDelayMs", 100)) * time.Millisecond,
+		maxDelay:    time.Duration(web.AppConfig.DefaultInt("upstreamRetryMaxDelayMs", 2000)) * time.Millisecond,
+		deadline:    time.Duration(web.AppConfig.DefaultInt("upstreamRequestDeadlineMs", 15000)) * time.Millisecond,
+	}
+
+	if len(baseURLs) > 1 {
+		client.endpoints = newEndpointPool(
+			baseURLs,
+			parseEndpointWeights(web.AppConfig.DefaultStrings("upstreamEndpointWeights", nil)),
+			BalancingStrategy(web.AppConfig.DefaultString("upstreamBalancing", string(BalanceRoundRobin))),
+			web.AppConfig.DefaultFloat("upstreamEndpointMaxErrorRate", 0.5),
+			web.AppConfig.DefaultInt("upstreamEndpointMinRequests", 5),
+			time.Duration(web.AppConfig.DefaultInt("upstreamEndpointEjectSeconds", 30))*time.Second,
+		)
+	}
+
+	// The document cache is disabled unless upstreamCacheTTLSeconds is set
+	if ttl := web.AppConfig.DefaultInt("upstreamCacheTTLSeconds", 0); ttl > 0 {
+		client.cache = newDocumentCache(
+			time.Duration(ttl)*time.Second,
+			time.Duration(web.AppConfig.DefaultInt("upstreamCacheStaleSeconds", 0))*time.Second,
+			time.Duration(web.AppConfig.DefaultInt("upstreamCacheMissingSeconds", 30))*time.Second,
+			web.AppConfig.DefaultInt("upstreamCacheMaxEntries", 1000),
+		)
+	}
+
+	return client
+}
+
+// parseEndpointWeights reads the upstreamEndpointWeights entries, in endpoint
+// order. An entry that is not a positive whole number leaves its endpoint at
+// the default weight of 1.
+func parseEndpointWeights(specs []string) []int {
+	weights := make([]int, len(specs))
+	for i, spec := range specs {
+		weight, err := strconv.Atoi(strings.TrimSpace(spec))
+		if err != nil || weight < 1 {
+			log.Printf("malformed upstream endpoint weight %q for endpoint %d, using weight 1", spec, i+1)
+			weight = 1
+		}
+		weights[i] = weight
+	}
+	return weights
+}
+
+// PropertyURL builds the upstream URL for a single property document.
+func (c *UpstreamClient) PropertyURL(propertyId, languageCode string) string {
+	return propertyURL(c.BaseURL, propertyId, languageCode)
+}
+
+func propertyURL(baseURL, propertyId, languageCode string) string {
+	query := url.Values{}
+	query.Set("propertyId", propertyId)
+	query.Set("languageCode", languageCode)
+	return baseURL + "?" + query.Encode()
+}
+
+// FetchPropertyDocument returns the raw upstream document holding the S3, OS
+// and S3-Gallery blocks of a property in the first language of languages the
+// upstream has content for. The served language is reported in FetchMeta.
+func (c *UpstreamClient) FetchPropertyDocument(ctx context.Context, propertyId string, languages []string) (map[string]interface{}, FetchMeta, error) {
+	var meta FetchMeta
+	var originalData map[string]interface{}
+	var err error
+
+	for _, languageCode := range languages {
+		var languageMeta FetchMeta
+		originalData, languageMeta, err = c.fetchLanguage(ctx, propertyId, languageCode)
+		meta = meta.Merge(languageMeta)
+		meta.Language = languageCode
+
+		if !isMissingLanguage(originalData, err) {
+			break
+		}
+		log.Printf("no %s content for property %s upstream", languageCode, propertyId)
+	}
+
+	return originalData, meta, err
+}
+
+// isMissingLanguage reports whether the upstream had nothing for a language,
+// either answering 404 or returning a document without any blocks.
+func isMissingLanguage(originalData map[string]interface{}, err error) bool {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Kind == UpstreamErrorStatus && upstreamErr.StatusCode == http.StatusNotFound
+	}
+	if err != nil {
+		return false
+	}
+	for _, block := range originalData {
+		if block != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchLanguage returns the document for one language, serving it from the
+// cache when enabled. A 404 is cached too, for a shorter time.
+func (c *UpstreamClient) fetchLanguage(ctx context.Context, propertyId, languageCode string) (map[string]interface{}, FetchMeta, error) {
+	key := documentCacheKey(propertyId, languageCode)
+
+	if c.cache == nil {
+		originalData, meta, err := c.fetchShared(ctx, key, propertyId, languageCode)
+		meta.CacheStatus = CacheBypass
+		return originalData, meta, err
+	}
+
+	if originalData, status, err := c.cache.Get(key); status != CacheMiss {
+		if status == CacheStale {
+			c.refreshInBackground(key, propertyId, languageCode)
+		}
+		return originalData, FetchMeta{CacheStatus: status}, err
+	}
+
+	originalData, meta, err := c.fetchShared(ctx, key, propertyId, languageCode)
+	meta.CacheStatus = CacheMiss
+	if err != nil {
+		if isMissingLanguage(nil, err) {
+			c.cache.SetMissing(key, err)
+		}
+		return nil, meta, err
+	}
+	c.cache.Set(key, originalData)
+
+	return originalData, meta, nil
+}
+
+// refreshInBackground replaces a stale cache entry without blocking the caller.
+func (c *UpstreamClient) refreshInBackground(key, propertyId, languageCode string) {
+	if !c.cache.beginRefresh(key) {
+		return
+	}
+
+	go func() {
+		defer c.cache.finishRefresh(key)
+
+		originalData, _, err := c.fetchShared(context.Background(), key, propertyId, languageCode)
+		if err != nil {
+			log.Printf("failed to refresh cached document %s: %v", key, err)
+			return
+		}
+		c.cache.Set(key, originalData)
+	}()
+}
+
+// fetchShared downloads the document, joining an identical request that is
+// already in flight rather than starting another one. The caller stops
+// waiting when ctx is done; the shared request itself is bounded by the
+// retry policy's deadline.
+func (c *UpstreamClient) fetchShared(ctx context.Context, key, propertyId, languageCode string) (map[string]interface{}, FetchMeta, error) {
+	originalData, meta, _, err := c.inflight.Do(ctx, key, func(callCtx context.Context) (map[string]interface{}, FetchMeta, error) {
+		if c.retry != nil && c.retry.deadline > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(callCtx, c.retry.deadline)
+			defer cancel()
+		}
+		return c.fetchWithRetries(callCtx, key, propertyId, languageCode)
+	})
+	var upstreamErr *UpstreamError
+	if err != nil && !errors.As(err, &upstreamErr) {
+		// The caller's own context ended before the shared request finished
+		return nil, meta, classifyTransportError(c.PropertyURL(propertyId, languageCode), err)
+	}
+	return originalData, meta, err
+}
+
+// fetchWithRetries retries temporary upstream failures with exponential
+// backoff until the attempts run out or ctx would expire before the next try.
+func (c *UpstreamClient) fetchWithRetries(ctx context.Context, key, propertyId, languageCode string) (map[string]interface{}, FetchMeta, error) {
+	policy := c.retry
+	if policy == nil {
+		policy = &retryPolicy{maxAttempts: 1}
+	}
+
+	for attempt := 1; ; attempt++ {
+		meta := FetchMeta{Attempts: attempt}
+
+		originalData, err := c.fetchThroughBreaker(ctx, propertyId, languageCode)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("upstream request for %s succeeded after %d attempts", key, attempt)
+			}
+			return originalData, meta, nil
+		}
+
+		var upstreamErr *UpstreamError
+		if !errors.As(err, &upstreamErr) || !upstreamErr.Temporary() || attempt >= policy.maxAttempts || ctx.Err() != nil {
+			if attempt > 1 {
+				log.Printf("upstream request for %s failed after %d attempts: %v", key, attempt, err)
+			}
+			return nil, meta, err
+		}
+
+		delay := policy.backoff(attempt, upstreamErr.RetryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			log.Printf("upstream request for %s failed after %d attempts, no time left to retry: %v", key, attempt, err)
+			return nil, meta, err
+		}
+
+		log.Printf("upstream request for %s failed on attempt %d/%d, retrying in %s: %v", key, attempt, policy.maxAttempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("upstream request for %s abandoned after %d attempts: %v", key, attempt, ctx.Err())
+			return nil, meta, classifyTransportError(upstreamErr.URL, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// fetchThroughBreaker fails fast while the circuit breaker is open and feeds
+// the outcome of every upstream call back into it.
+func (c *UpstreamClient) fetchThroughBreaker(ctx context.Context, propertyId, languageCode string) (map[string]interface{}, error) {
+	if c.breaker == nil {
+		return c.fetchPropertyDocument(ctx, propertyId, languageCode)
+	}
+
+	if allowed, retryAfter := c.breaker.Allow(); !allowed {
+		return nil, &UpstreamError{Kind: UpstreamErrorCircuitOpen, URL: c.BaseURL, RetryAfter: retryAfter}
+	}
+
+	originalData, err := c.fetchPropertyDocument(ctx, propertyId, languageCode)
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.Kind == UpstreamErrorCanceled {
+		// Abandoned requests say nothing about the upstream's health
+		c.breaker.Release()
+		return nil, err
+	}
+	c.breaker.Record(upstreamErr != nil && upstreamErr.Temporary())
+
+	return originalData, err
+}
+
+// fetchPropertyDocument returns one document, replaying it from the
+// fixtures or downloading it and, in record mode, saving it as a fixture.
+func (c *UpstreamClient) fetchPropertyDocument(ctx context.Context, propertyId, languageCode string) (map[string]interface{}, error) {
+	if c.mode == UpstreamModeReplay {
+		return c.fixtures.Load(propertyId, languageCode)
+	}
+
+	originalData, err := c.downloadPropertyDocument(ctx, propertyId, languageCode)
+	if err == nil && c.mode == UpstreamModeRecord {
+		if saveErr := c.fixtures.Save(propertyId, languageCode, originalData); saveErr != nil {
+			log.Printf("failed to record fixture for %s|%s: %v", propertyId, languageCode, saveErr)
+		}
+	}
+	return originalData, err
+}
+
+// downloadPropertyDocument downloads and decodes one document from the
+// upstream. With several endpoints configured, temporary failures fail over
+// to the next endpoint in the pool's order.
+func (c *UpstreamClient) downloadPropertyDocument(ctx context.Context, propertyId, languageCode string) (map[string]interface{}, error) {
+	if c.endpoints == nil {
+		return c.fetchFromEndpoint(ctx, c.BaseURL, propertyId, languageCode)
+	}
+
+	var err error
+	for _, endpoint := range c.endpoints.order() {
+		started := time.Now()
+		var originalData map[string]interface{}
+		originalData, err = c.fetchFromEndpoint(ctx, endpoint.baseURL, propertyId, languageCode)
+
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) && upstreamErr.Kind == UpstreamErrorCanceled {
+			return nil, err
+		}
+		failed := upstreamErr != nil && upstreamErr.Temporary()
+		c.endpoints.record(endpoint, time.Since(started), failed)
+		if !failed || ctx.Err() != nil {
+			return originalData, err
+		}
+		log.Printf("upstream endpoint %s failed, trying the next one: %v", endpoint.baseURL, err)
+	}
+	return nil, err
+}
+
+// fetchFromEndpoint downloads and decodes one document from one endpoint.
+func (c *UpstreamClient) fetchFromEndpoint(ctx context.Context, baseURL, propertyId, languageCode string) (map[string]interface{}, error) {
+	externalAPIURL := propertyURL(baseURL, propertyId, languageCode)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, externalAPIURL, nil)
+	if err != nil {
+		return nil, &UpstreamError{Kind: UpstreamErrorConfig, URL: externalAPIURL, Err: err}
+	}
+	for name, values := range c.Headers {
+		req.Header[name] = values
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, classifyTransportError(externalAPIURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &UpstreamError{
+			Kind:       UpstreamErrorStatus,
+			StatusCode: resp.StatusCode,
+			URL:        externalAPIURL,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	var originalData map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&originalData); err != nil {
+		return nil, classifyDecodeError(externalAPIURL, resp.StatusCode, err)
+	}
+
+	return originalData, nil
+}
+
+func classifyTransportError(externalAPIURL string, err error) *UpstreamError {
+	if errors.Is(err, context.Canceled) {
+		return &UpstreamError{Kind: UpstreamErrorCanceled, URL: externalAPIURL, Err: err}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &UpstreamError{Kind: UpstreamErrorTimeout, URL: externalAPIURL, Err: err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &UpstreamError{Kind: UpstreamErrorTimeout, URL: externalAPIURL, Err: err}
+	}
+	return &UpstreamError{Kind: UpstreamErrorTransport, URL: externalAPIURL, Err: err}
+}
+
+func classifyDecodeError(externalAPIURL string, statusCode int, err error) *UpstreamError {
+	if errors.Is(err, context.Canceled) {
+		return &UpstreamError{Kind: UpstreamErrorCanceled, StatusCode: statusCode, URL: externalAPIURL, Err: err}
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &UpstreamError{Kind: UpstreamErrorTimeout, StatusCode: statusCode, URL: externalAPIURL, Err: err}
+	}
+	return &UpstreamError{Kind: UpstreamErrorDecode, StatusCode: statusCode, URL: externalAPIURL, Err: err}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"beego-api-service/internal/fakeupstream"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamClientFetchPropertyDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		propertyID   string
+		scenario     fakeupstream.Scenario
+		expectedKind UpstreamErrorKind
+		expectError  bool
+	}{
+		{
+			name:        "Success",
+			propertyID:  "HA-3213808988",
+			expectError: false,
+		},
+		{
+			name:         "Upstream not found",
+			propertyID:   "HA-404",
+			expectedKind: UpstreamErrorStatus,
+			expectError:  true,
+		},
+		{
+			name:         "Malformed JSON",
+			propertyID:   "HA-3213808988",
+			scenario:     fakeupstream.Scenario{TruncateRate: 1},
+			expectedKind: UpstreamErrorDecode,
+			expectError:  true,
+		},
+		{
+			name:         "Slow upstream",
+			propertyID:   "HA-3213808988",
+			scenario:     fakeupstream.Scenario{LatencyMs: 200},
+			expectedKind: UpstreamErrorTimeout,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := fakeupstream.New(fakeupstream.FixturesDir(), tt.scenario, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.propertyID, r.URL.Query().Get("propertyId"))
+				assert.Equal(t, "en", r.URL.Query().Get("languageCode"))
+				assert.Equal(t, "application/json", r.Header.Get("Accept"))
+				upstream.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			client := NewUpstreamClient(server.URL, 100*time.Millisecond)
+			result, _, err := client.FetchPropertyDocument(context.Background(), tt.propertyID, []string{"en"})
+
+			if tt.expectError {
+				var upstreamErr *UpstreamError
+				assert.True(t, errors.As(err, &upstreamErr))
+				assert.Equal(t, tt.expectedKind, upstreamErr.Kind)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, result, "S3")
+			}
+		})
+	}
+}
+
+func TestUpstreamClientPropertyURL(t *testing.T) {
+	client := NewUpstreamClient("http://example.com/dynamodb-s3-os", time.Second)
+
+	assert.Equal(t, "http://example.com/dynamodb-s3-os?languageCode=en&propertyId=HA-1%262", client.PropertyURL("HA-1&2", "en"))
+}
+
+func TestDefaultUpstreamClient(t *testing.T) {
+	upstream := fakeupstream.New(fakeupstream.FixturesDir(), fakeupstream.Scenario{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "trace-1", r.Header.Get("X-Trace-Id"))
+		upstream.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	web.AppConfig.Set("upstreamAuthToken", "secret")
+	web.AppConfig.Set("upstreamHeaders", "X-Trace-Id: trace-1")
+	web.AppConfig.Set("externalAPIBaseURL", server.URL)
+	defer web.AppConfig.Set("upstreamAuthToken", "")
+	defer web.AppConfig.Set("upstreamHeaders", "")
+
+	client, err := DefaultUpstreamClient()
+	assert.NoError(t, err)
+
+	sameClient, err := DefaultUpstreamClient()
+	assert.NoError(t, err)
+	assert.Same(t, client, sameClient)
+
+	_, _, err = client.FetchPropertyDocument(context.Background(), "HA-3213808988", []string{"en"})
+	assert.NoError(t, err)
+}
+
+func TestDefaultUpstreamClientConfigError(t *testing.T) {
+	web.AppConfig.Set("externalAPIBaseURL", "")
+
+	client, err := DefaultUpstreamClient()
+
+	var upstreamErr *UpstreamError
+	assert.True(t, errors.As(err, &upstreamErr))
+	assert.Equal(t, UpstreamErrorConfig, upstreamErr.Kind)
+	assert.Nil(t, client)
+}
+
+func TestUpstreamErrorTemporary(t *testing.T) {
+	assert.True(t, (&UpstreamError{Kind: UpstreamErrorTransport}).Temporary())
+	assert.True(t, (&UpstreamError{Kind: UpstreamErrorTimeout}).Temporary())
+	assert.True(t, (&UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusTooManyRequests}).Temporary())
+	assert.True(t, (&UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusBadGateway}).Temporary())
+	assert.False(t, (&UpstreamError{Kind: UpstreamErrorStatus, StatusCode: http.StatusNotFound}).Temporary())
+	assert.False(t, (&UpstreamError{Kind: UpstreamErrorDecode}).Temporary())
+}
+
+func TestParseEndpointWeights(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		want  []int
+	}{
+		{name: "Weights in endpoint order", specs: []string{"2", " 1 "}, want: []int{2, 1}},
+		{name: "Malformed weight defaults to 1", specs: []string{"x", "3"}, want: []int{1, 3}},
+		{name: "Non-positive weight defaults to 1", specs: []string{"0", "-2"}, want: []int{1, 1}},
+		{name: "No weights", specs: nil, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseEndpointWeights(tt.specs))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package structs
+
+// Statuses of one property in a bulk response.
+const (
+	BulkItemOK       = "ok"
+	BulkItemNotFound = "not_found"
+	BulkItemError    = "error"
+)
+
+// BulkItem is the outcome of fetching one requested property. Data holds the
+// property details, or only the selected fields of them, when Status is ok;
+// Error is set otherwise. LatencyMs is how long the fetch took.
+type BulkItem struct {
+	ID         string         `json:"ID"`
+	Status     string         `json:"Status"`
+	Error      *ErrorResponse `json:"Error,omitempty"`
+	Data       interface{}    `json:"Data,omitempty"`
+	Provenance Provenance     `json:"Provenance,omitempty"`
+	LatencyMs  int64          `json:"LatencyMs"`
+}
+
+// BulkPropertyResponse has one item per requested ID, in request order.
+type BulkPropertyResponse struct {
+	Summary BulkSummary `json:"Summary"`
+	Items   []BulkItem  `json:"Items"`
+}
+
+type BulkSummary struct {
+	Total    int `json:"Total"`
+	OK       int `json:"OK"`
+	NotFound int `json:"NotFound"`
+	Error    int `json:"Error"`
+}
+
+// BulkPropertyFetchRequest is the JSON body of POST /propertyList. Only IDs
+// is required.
+type BulkPropertyFetchRequest struct {
+	IDs            []string `json:"IDs"`
+	Source         string   `json:"Source,omitempty"`
+	Fields         []string `json:"Fields,omitempty"`
+	Language       string   `json:"Language,omitempty"`
+	WithProvenance bool     `json:"WithProvenance,omitempty"`
+}
+
+// BulkStreamSummary is the last record of a streamed bulk response. Total
+// counts every requested ID, so properties skipped after Error are the ones
+// not counted as OK, NotFound or Error.
+type BulkStreamSummary struct {
+	Summary BulkSummary    `json:"Summary"`
+	Error   *ErrorResponse `json:"Error,omitempty"`
+}
@@ -0,0 +1,33 @@
+package structs
+
+// FieldDiscrepancy is one field on which the S3 and OS views of a property
+// disagree.
+type FieldDiscrepancy struct {
+	Field string      `json:"Field"`
+	S3    interface{} `json:"S3"`
+	OS    interface{} `json:"OS"`
+}
+
+// DiscrepancyReport lists every field on which the S3 and OS views of one
+// property disagree. Error is set instead when the property could not be
+// compared.
+type DiscrepancyReport struct {
+	ID            string             `json:"ID"`
+	Discrepancies []FieldDiscrepancy `json:"Discrepancies"`
+	Error         *ErrorResponse     `json:"Error,omitempty"`
+}
+
+// ReconciliationReport compares the S3 and OS views across many properties.
+type ReconciliationReport struct {
+	Properties []DiscrepancyReport   `json:"Properties"`
+	Summary    ReconciliationSummary `json:"Summary"`
+}
+
+type ReconciliationSummary struct {
+	Total             int `json:"Total"`
+	Consistent        int `json:"Consistent"`
+	WithDiscrepancies int `json:"WithDiscrepancies"`
+	Failed            int `json:"Failed"`
+	// Fields counts the properties that disagree on each field.
+	Fields map[string]int `json:"Fields"`
+}
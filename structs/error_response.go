@@ -0,0 +1,31 @@
+package structs
+
+// ErrorResponse is the body of a failed request. Code is stable and meant
+// for programs; Message is meant for people.
+type ErrorResponse struct {
+	Code    string       `json:"Code"`
+	Message string       `json:"Message"`
+	Fields  []FieldError `json:"Fields,omitempty"`
+}
+
+// FieldError describes one field of an upstream document that does not match
+// the expected schema.
+type FieldError struct {
+	Path   string `json:"Path"`
+	Reason string `json:"Reason"`
+}
+
+// Problem is the RFC 7807 problem details body of an error response. Type,
+// Title, Status, Detail and Instance are defined by the RFC; Code is the
+// stable error code of ErrorResponse and RequestID matches the X-Request-ID
+// response header.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
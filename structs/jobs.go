@@ -0,0 +1,28 @@
+package structs
+
+// JobProgress reports how far a property fetch job has got. Done counts the
+// properties fetched, Failed those that could not be fetched, and Pending
+// those not fetched yet.
+type JobProgress struct {
+	ID        string `json:"ID"`
+	Status    string `json:"Status"`
+	Total     int    `json:"Total"`
+	Done      int    `json:"Done"`
+	Failed    int    `json:"Failed"`
+	Pending   int    `json:"Pending"`
+	CreatedAt string `json:"CreatedAt"`
+	UpdatedAt string `json:"UpdatedAt"`
+}
+
+// JobResultsPage is one page of the items of a job, in the order they were
+// fetched rather than the order of the request. Items are only added after
+// the last one, so a page never changes once it is full and a client can
+// resume from NextOffset while the job runs. NextOffset is set while more
+// items are available.
+type JobResultsPage struct {
+	JobID      string     `json:"JobID"`
+	Offset     int        `json:"Offset"`
+	Limit      int        `json:"Limit"`
+	NextOffset *int       `json:"NextOffset,omitempty"`
+	Items      []BulkItem `json:"Items"`
+}
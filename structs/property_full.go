@@ -0,0 +1,11 @@
+package structs
+
+// PropertyFullResponse bundles every view of a property built from one upstream document.
+// Errors is keyed by the name of each section, OSDetails or Images, that
+// could not be built; such a section is left empty.
+type PropertyFullResponse struct {
+	Details   PropertyDetailsResponse   `json:"Details"`
+	OSDetails PropertyDetailsResponse   `json:"OSDetails"`
+	Images    ImagesResponse            `json:"Images"`
+	Errors    map[string]*ErrorResponse `json:"Errors,omitempty"`
+}
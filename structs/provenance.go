@@ -0,0 +1,21 @@
+package structs
+
+// FieldProvenance tells where the value of one response field came from.
+// Source is S3, OS or default (no upstream value); Key is the upstream key
+// the value was read from. Override is set when a per-field merge rule chose
+// the source.
+type FieldProvenance struct {
+	Source   string `json:"Source"`
+	Key      string `json:"Key,omitempty"`
+	Override bool   `json:"Override,omitempty"`
+}
+
+// Provenance maps response field paths, such as Property.Price, to where
+// their values came from.
+type Provenance map[string]FieldProvenance
+
+// PropertyDetailsWithProvenance is returned when a client asks for provenance.
+type PropertyDetailsWithProvenance struct {
+	Details    PropertyDetailsResponse `json:"Details"`
+	Provenance Provenance              `json:"Provenance,omitempty"`
+}